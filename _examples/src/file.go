@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/sdorra/welfare"
 	"github.com/sdorra/welfare/files"
@@ -14,19 +15,21 @@ three: {{.three}}
 `
 
 func main() {
+	runner := welfare.NewRunner()
+
 	file := files.NewFileModule("/etc/issue.net", files.Absent)
-	run(file, "remove file")
+	runner.Add("remove file", file)
 
 	file = files.NewFileModule("/etc/welfare", files.Directory)
 	file.FileMode = 0700
-	run(file, "create directory")
+	runner.Add("create directory", file)
 
 	file = files.NewFileModule("/etc/welfare/message", files.File)
 	file.Content = "# welfare message file"
-	run(file, "create message file")
+	runner.Add("create message file", file)
 
 	copy := files.NewCopyModule("/etc/issue", "/etc/welfare/issue")
-	run(copy, "copy issue file")
+	runner.Add("copy issue file", copy)
 
 	context := make(map[string]string)
 	context["one"] = "1"
@@ -34,13 +37,16 @@ func main() {
 	context["three"] = "3 (three|drei)"
 
 	template := files.NewTemplateModule("/etc/welfare/config", configTemplate, context)
-	run(template, "create config file")
-}
+	runner.Add("create config file", template)
+
+	recap, err := runner.Run()
+	for _, taskResult := range recap.Results {
+		fmt.Println(taskResult.Name, ":", taskResult.Result.Status)
+	}
+	fmt.Println(recap)
 
-func run(module welfare.Module, action string) {
-	changed, err := module.Run()
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	fmt.Println(action, ":", changed)
 }
@@ -2,30 +2,40 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
 	"github.com/sdorra/welfare/packages"
 )
 
 func main() {
+	runner := welfare.NewRunner()
+
 	aptKey := packages.NewAptKeyModule("D742B261", packages.Present)
-	exec(aptKey, "add scm-manager key")
+	runner.Add("add scm-manager key", aptKey)
 
 	aptRepo := packages.NewAptRepositoryModule("scm-manager", packages.Present)
 	aptRepo.Repository = "deb http://maven.scm-manager.org/nexus/content/repositories/releases ./"
-	exec(aptRepo, "add scm-manager repository")
+	runner.Add("add scm-manager repository", aptRepo)
 
 	apt := packages.NewAptModule("openjdk-8-jre", packages.Present)
-	exec(apt, "install java")
+	runner.Add("install java", apt)
 
 	apt = packages.NewAptModule("scm-server", packages.Present)
-	exec(apt, "install scm-server")
-}
+	runner.Add("install scm-server", apt)
+
+	contextPath := files.NewXMLModule("/opt/scm-server/conf/server-config.xml", "//context-path/text()", "/")
+	runner.Add("serve scm-manager at the root path", contextPath)
+
+	recap, err := runner.Run()
+	for _, taskResult := range recap.Results {
+		fmt.Println(taskResult.Name, ":", taskResult.Result.Status)
+	}
+	fmt.Println(recap)
 
-func exec(module welfare.Module, action string) {
-	changed, err := module.Run()
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	fmt.Println(action, ":", changed)
 }
@@ -0,0 +1,209 @@
+package welfare
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Arguments are the raw arguments of a module, as they are declared in a playbook
+type Arguments map[string]interface{}
+
+// ArgumentError describes an invalid argument of a module
+type ArgumentError struct {
+	Field  string
+	Reason string
+}
+
+func (err *ArgumentError) Error() string {
+	return fmt.Sprintf("invalid argument %s: %s", err.Field, err.Reason)
+}
+
+// ArgumentDecoder is implemented by types, which decode the raw value of an argument by themselves
+type ArgumentDecoder interface {
+	DecodeArgument(raw interface{}) error
+}
+
+var (
+	fileModeType        = reflect.TypeOf(os.FileMode(0))
+	argumentDecoderType = reflect.TypeOf((*ArgumentDecoder)(nil)).Elem()
+)
+
+// Decode stores the arguments in the struct pointed to by target. The fields of the struct are mapped with the
+// welfare tag, e.g. `welfare:"path,required"`, the fields of embedded structs without tag are mapped as well. Fields
+// which are not part of the arguments keep their value, so that defaults can be assigned before Decode is called.
+// Unknown, missing required and mistyped arguments are reported as ArgumentError.
+func (args Arguments) Decode(target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("decode target must be a pointer to a struct, got %T", target)
+	}
+	value = value.Elem()
+
+	fields := argumentFields(value)
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.name] = true
+	}
+
+	unknown := []string{}
+	for name := range args {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &ArgumentError{Field: unknown[0], Reason: "is unknown"}
+	}
+
+	for _, field := range fields {
+		raw, ok := args[field.name]
+		if !ok || raw == nil {
+			if hasOption(field.options, "required") {
+				return &ArgumentError{Field: field.name, Reason: "is required"}
+			}
+			continue
+		}
+
+		err := decodeValue(field.value, raw)
+		if err != nil {
+			return &ArgumentError{Field: field.name, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// argumentField is a field of the decode target, which is mapped to an argument
+type argumentField struct {
+	name    string
+	options []string
+	value   reflect.Value
+}
+
+// argumentFields returns the mapped fields of the struct, embedded structs without welfare tag contribute their fields
+func argumentFields(value reflect.Value) []argumentField {
+	fields := []argumentField{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, options := argumentTag(field)
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, argumentFields(value.Field(i))...)
+		} else if name != "" {
+			fields = append(fields, argumentField{name: name, options: options, value: value.Field(i)})
+		}
+	}
+	return fields
+}
+
+// argumentTag returns the name and the options of the welfare tag, the name is empty if the field is not mapped
+func argumentTag(field reflect.StructField) (string, []string) {
+	tag := field.Tag.Get("welfare")
+	if tag == "" || tag == "-" {
+		return "", nil
+	}
+
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+func hasOption(options []string, option string) bool {
+	for _, candidate := range options {
+		if candidate == option {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeValue(field reflect.Value, raw interface{}) error {
+	if field.CanAddr() && field.Addr().Type().Implements(argumentDecoderType) {
+		return field.Addr().Interface().(ArgumentDecoder).DecodeArgument(raw)
+	}
+	if field.Type() == fileModeType {
+		return decodeFileMode(field, raw)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch value := raw.(type) {
+		case string:
+			field.SetString(value)
+		case int, float64, bool:
+			field.SetString(fmt.Sprint(value))
+		default:
+			return typeError("string", raw)
+		}
+	case reflect.Bool:
+		value, ok := raw.(bool)
+		if !ok {
+			return typeError("boolean", raw)
+		}
+		field.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, ok := raw.(int)
+		if !ok {
+			return typeError("integer", raw)
+		}
+		field.SetInt(int64(value))
+	case reflect.Slice:
+		values, ok := raw.([]interface{})
+		if !ok {
+			return typeError("list", raw)
+		}
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			err := decodeValue(slice.Index(i), value)
+			if err != nil {
+				return errors.Errorf("item %d: %v", i, err)
+			}
+		}
+		field.Set(slice)
+	case reflect.Map:
+		values, ok := raw.(map[string]interface{})
+		if !ok || field.Type().Key().Kind() != reflect.String {
+			return typeError("map", raw)
+		}
+		mapping := reflect.MakeMap(field.Type())
+		for key, value := range values {
+			element := reflect.New(field.Type().Elem()).Elem()
+			err := decodeValue(element, value)
+			if err != nil {
+				return errors.Errorf("key %s: %v", key, err)
+			}
+			mapping.SetMapIndex(reflect.ValueOf(key), element)
+		}
+		field.Set(mapping)
+	case reflect.Interface:
+		field.Set(reflect.ValueOf(raw))
+	default:
+		return errors.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func decodeFileMode(field reflect.Value, raw interface{}) error {
+	switch value := raw.(type) {
+	case int:
+		field.SetUint(uint64(value))
+	case string:
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return errors.Errorf("expected octal file mode, got %s", value)
+		}
+		field.SetUint(mode)
+	default:
+		return typeError("file mode", raw)
+	}
+	return nil
+}
+
+func typeError(expected string, raw interface{}) error {
+	return errors.Errorf("expected %s, got %T", expected, raw)
+}
@@ -0,0 +1,86 @@
+package welfare_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/stretchr/testify/assert"
+)
+
+type testArguments struct {
+	Path    string                 `welfare:"path,required"`
+	Force   bool                   `welfare:"force"`
+	Retries int                    `welfare:"retries"`
+	Mode    os.FileMode            `welfare:"mode"`
+	Tags    []string               `welfare:"tags"`
+	Context map[string]interface{} `welfare:"context"`
+}
+
+func TestArguments_Decode(t *testing.T) {
+	args := welfare.Arguments{
+		"path":    "/etc/welfare",
+		"force":   true,
+		"retries": 3,
+		"mode":    "0700",
+		"tags":    []interface{}{"one", "two"},
+		"context": map[string]interface{}{"name": "sorbot"},
+	}
+
+	decoded := testArguments{}
+	err := args.Decode(&decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/welfare", decoded.Path)
+	assert.True(t, decoded.Force)
+	assert.Equal(t, 3, decoded.Retries)
+	assert.Equal(t, os.FileMode(0700), decoded.Mode)
+	assert.Equal(t, []string{"one", "two"}, decoded.Tags)
+	assert.Equal(t, "sorbot", decoded.Context["name"])
+}
+
+func TestArguments_DecodeKeepsDefaults(t *testing.T) {
+	decoded := testArguments{Retries: 5}
+	err := welfare.Arguments{"path": "/etc"}.Decode(&decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, decoded.Retries)
+}
+
+func TestArguments_DecodeWithMissingRequiredArgument(t *testing.T) {
+	err := welfare.Arguments{"force": true}.Decode(&testArguments{})
+	assert.EqualError(t, err, "invalid argument path: is required")
+}
+
+func TestArguments_DecodeWithUnknownArgument(t *testing.T) {
+	err := welfare.Arguments{"path": "/etc", "mdoe": "0700"}.Decode(&testArguments{})
+	assert.EqualError(t, err, "invalid argument mdoe: is unknown")
+}
+
+func TestArguments_DecodeWithWrongType(t *testing.T) {
+	err := welfare.Arguments{"path": "/etc", "force": "yes please"}.Decode(&testArguments{})
+	assert.EqualError(t, err, "invalid argument force: expected boolean, got string")
+
+	err = welfare.Arguments{"path": "/etc", "mode": "rwx"}.Decode(&testArguments{})
+	assert.EqualError(t, err, "invalid argument mode: expected octal file mode, got rwx")
+}
+
+type ownerArguments struct {
+	Owner string `welfare:"owner"`
+	UID   int    `welfare:"uid"`
+}
+
+type embeddingArguments struct {
+	ownerArguments
+	Path string `welfare:"path,required"`
+}
+
+func TestArguments_DecodeEmbeddedStruct(t *testing.T) {
+	decoded := embeddingArguments{ownerArguments: ownerArguments{UID: -1}}
+	err := welfare.Arguments{"path": "/srv", "owner": "scm"}.Decode(&decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, "/srv", decoded.Path)
+	assert.Equal(t, "scm", decoded.Owner)
+	assert.Equal(t, -1, decoded.UID)
+
+	err = welfare.Arguments{"path": "/srv", "group": "scm"}.Decode(&decoded)
+	assert.EqualError(t, err, "invalid argument group: is unknown")
+}
@@ -0,0 +1,156 @@
+// Command welfare executes the tasks of a playbook on the local machine.
+//
+// Usage:
+//
+//	welfare [flags] playbook.yml
+//
+// The exit code is 0 if all tasks were executed successfully, 1 if a task has failed and 2 if changes were detected
+// in check mode. On SIGINT or SIGTERM the running tasks are canceled and no further tasks are started.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sdorra/welfare"
+	_ "github.com/sdorra/welfare/files"
+	_ "github.com/sdorra/welfare/packages"
+)
+
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitChanged = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("welfare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: welfare [flags] playbook")
+		flags.PrintDefaults()
+	}
+
+	check := flags.Bool("check", false, "report what would change, without changing anything")
+	keepGoing := flags.Bool("keep-going", false, "continue with the remaining tasks after a task has failed")
+	tags := flags.String("tags", "", "comma separated list of tags, only tasks with one of the tags are executed")
+	startAt := flags.String("start-at-task", "", "skip all tasks before the task with the given name")
+	workers := flags.Int("workers", 1, "maximum number of independent tasks, which are executed concurrently")
+	timeout := flags.Duration("timeout", 0, "default timeout of tasks, which do not declare their own timeout")
+	verbosity := verbosityFlag(0)
+	flags.Var(&verbosity, "v", "print messages and diffs, repeat to print the output of executed commands")
+	output := flags.String("output", "console", "format of the task output, console or json")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return exitFailed
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitFailed
+	}
+
+	var observer welfare.Observer
+	switch *output {
+	case "console":
+		observer = welfare.NewConsoleObserver(stdout, int(verbosity))
+	case "json":
+		observer = welfare.NewJSONObserver(stdout)
+	default:
+		fmt.Fprintf(stderr, "unknown output format %s\n", *output)
+		return exitFailed
+	}
+
+	playbook, err := welfare.LoadPlaybook(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailed
+	}
+
+	runner := playbook.Runner()
+	runner.Check = *check
+	runner.KeepGoing = *keepGoing
+	runner.StartAt = *startAt
+	runner.Workers = *workers
+	runner.Observer = observer
+	if *tags != "" {
+		runner.Tags = strings.Split(*tags, ",")
+	}
+	applyDefaultTimeout(runner.Tasks, *timeout)
+	applyDefaultTimeout(runner.Handlers, *timeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	recap, err := runner.RunContext(ctx)
+	if *output == "console" {
+		fmt.Fprintf(stdout, "\nRECAP %s\n", recap)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailed
+	}
+
+	if *check && recap.Changed > 0 {
+		return exitChanged
+	}
+	return exitOK
+}
+
+func applyDefaultTimeout(tasks []welfare.Task, timeout time.Duration) {
+	for index := range tasks {
+		if tasks[index].Timeout == 0 {
+			tasks[index].Timeout = timeout
+		}
+	}
+}
+
+// verbosityFlag counts how often the flag was specified, e.g. -v -v results in a verbosity of 2
+type verbosityFlag int
+
+func (flag *verbosityFlag) String() string {
+	return strconv.Itoa(int(*flag))
+}
+
+func (flag *verbosityFlag) Set(value string) error {
+	if value == "true" {
+		*flag++
+		return nil
+	}
+
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*flag = verbosityFlag(level)
+	return nil
+}
+
+func (flag *verbosityFlag) IsBoolFlag() bool {
+	return true
+}
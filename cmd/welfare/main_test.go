@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const playbook = `
+- name: create directory
+  tags: [directory]
+  file: {path: {{.}}/welfare, state: directory}
+- name: create message file
+  file: {path: {{.}}/welfare/message, content: "hello"}
+`
+
+func createPlaybook(t *testing.T, dir string) string {
+	content := bytes.Replace([]byte(playbook), []byte("{{.}}"), []byte(dir), -1)
+	playbookPath := path.Join(dir, "playbook.yml")
+	err := ioutil.WriteFile(playbookPath, content, 0644)
+	require.Nil(t, err)
+	return playbookPath
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{createPlaybook(t, dir)}, &stdout, &stderr)
+	assert.Equal(t, exitOK, exitCode)
+	assert.Contains(t, stdout.String(), "changed: create directory\n")
+	assert.Contains(t, stdout.String(), "changed: create message file\n")
+	assert.Contains(t, stdout.String(), "RECAP ok=0 changed=2 skipped=0 failed=0")
+
+	_, err = os.Stat(path.Join(dir, "welfare", "message"))
+	assert.Nil(t, err)
+}
+
+func TestRunInCheckMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"-check", "-v", "-tags", "directory", createPlaybook(t, dir)}, &stdout, &stderr)
+	assert.Equal(t, exitChanged, exitCode)
+	assert.Contains(t, stdout.String(), "created directory "+path.Join(dir, "welfare"))
+	assert.NotContains(t, stdout.String(), "create message file")
+
+	_, err = os.Stat(path.Join(dir, "welfare"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunWithJSONOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"-output", "json", createPlaybook(t, dir)}, &stdout, &stderr)
+	assert.Equal(t, exitOK, exitCode)
+	assert.Contains(t, stdout.String(), `{"event":"started","task":"create directory"`)
+	assert.Contains(t, stdout.String(), `{"event":"finished","task":"create message file"`)
+	assert.NotContains(t, stdout.String(), "RECAP")
+}
+
+func TestRunWithUnknownOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"-output", "xml", "playbook.yml"}, &stdout, &stderr)
+	assert.Equal(t, exitFailed, exitCode)
+	assert.Contains(t, stderr.String(), "unknown output format xml")
+}
+
+func TestRunWithoutPlaybook(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{}, &stdout, &stderr)
+	assert.Equal(t, exitFailed, exitCode)
+	assert.Contains(t, stderr.String(), "usage: welfare")
+}
+
+func TestVerbosityFlag(t *testing.T) {
+	verbosity := verbosityFlag(0)
+	assert.Nil(t, verbosity.Set("true"))
+	assert.Nil(t, verbosity.Set("true"))
+	assert.Equal(t, verbosityFlag(2), verbosity)
+
+	assert.Nil(t, verbosity.Set("0"))
+	assert.Equal(t, verbosityFlag(0), verbosity)
+
+	assert.Error(t, verbosity.Set("x"))
+}
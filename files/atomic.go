@@ -0,0 +1,154 @@
+package files
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// writeAtomic replaces the file at path with the content of the reader. The content is written to a temporary file in
+// the same directory, which is synced, gets the permissions and is renamed to path afterwards. So a reader of the file
+// sees either the old or the new content, but never a partially written file. If path is a symlink, the file the link
+// points to is replaced. Negative ids of the permissions keep the owner of the temporary file. If beforeRename is not
+// nil, it is called with the path of the complete temporary file and the file is only renamed if it returns nil.
+func writeAtomic(path string, reader io.Reader, perms permissions, beforeRename func(tempPath string) error) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		path = resolved
+	}
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tempFile, err := ioutil.TempFile(dir, "."+name+".welfare")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file for %s", path)
+	}
+
+	tempPath := tempFile.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			tempFile.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	_, err = io.Copy(tempFile, reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write content of %s", path)
+	}
+
+	err = tempFile.Sync()
+	if err != nil {
+		return errors.Wrapf(err, "failed to sync content of %s", path)
+	}
+
+	if perms.UID >= 0 || perms.GID >= 0 {
+		err = tempFile.Chown(perms.UID, perms.GID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to change owner of %s", path)
+		}
+	}
+
+	err = tempFile.Chmod(perms.FileMode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to change mode of %s", path)
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to close temporary file of %s", path)
+	}
+
+	if beforeRename != nil {
+		err = beforeRename(tempPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tempPath, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to rename temporary file to %s", path)
+	}
+	renamed = true
+
+	return syncDirectory(dir)
+}
+
+// syncDirectory persists the rename of a file, by syncing the directory which contains the file
+func syncDirectory(dir string) error {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open directory %s", dir)
+	}
+	defer directory.Close()
+
+	err = directory.Sync()
+	if err != nil {
+		return errors.Wrapf(err, "failed to sync directory %s", dir)
+	}
+	return nil
+}
+
+// replaceWithLink creates a link with a temporary name next to path and renames it to path, so that an existing file
+// or link at path is replaced atomically. The link is created by calling create with the temporary path.
+func replaceWithLink(path string, create func(tempPath string) error) error {
+	dir, name := filepath.Split(path)
+
+	var tempPath string
+	for i := 0; ; i++ {
+		tempPath = filepath.Join(dir, "."+name+".welfare"+strconv.Itoa(os.Getpid())+"-"+strconv.Itoa(i))
+		err := create(tempPath)
+		if err == nil {
+			break
+		} else if !os.IsExist(err) {
+			return errors.Wrapf(err, "failed to create link for %s", path)
+		}
+	}
+
+	err := os.Rename(tempPath, path)
+	if err != nil {
+		os.Remove(tempPath)
+		return errors.Wrapf(err, "failed to rename temporary link to %s", path)
+	}
+	return nil
+}
+
+// replaceOptions configures how the content of a file is replaced
+type replaceOptions struct {
+	backupOptions
+	validation
+}
+
+// replace replaces the content of the target atomically with the content of the reader. The new content is validated
+// first, afterwards the old content is backed up and the path of the backup is stored in the result.
+func (options replaceOptions) replace(ctx context.Context, result *welfare.Result, target fileInfo, reader io.Reader, expected permissions) error {
+	return writeAtomic(target.Path, reader, replacementPermissions(target, expected), func(tempPath string) error {
+		err := options.validate(ctx, result, tempPath)
+		if err != nil {
+			return err
+		}
+
+		result.Backup, err = options.backupFile(target)
+		return err
+	})
+}
+
+// replacementPermissions returns the permissions of the file which replaces the target. Permissions which are not
+// specified by expected are taken from the target, if it exists.
+func replacementPermissions(target fileInfo, expected permissions) permissions {
+	if target.State == Absent {
+		target.permissions = permissions{FileMode: 0644, UID: -1, GID: -1}
+	}
+	return mergeFilePermissions(target, expected).permissions
+}
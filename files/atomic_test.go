@@ -0,0 +1,86 @@
+package files
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "config")
+	err = writeAtomic(target, strings.NewReader("hello"), permissions{FileMode: 0640, UID: -1, GID: -1}, nil)
+	require.Nil(t, err)
+
+	content, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), stat.Mode().Perm())
+	assertOnlyFile(t, dir, "config")
+}
+
+func TestWriteAtomicKeepsTargetOnFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "config")
+	require.Nil(t, ioutil.WriteFile(target, []byte("original"), 0644))
+
+	reader := io.MultiReader(strings.NewReader("half"), &failingReader{})
+	err = writeAtomic(target, reader, permissions{FileMode: 0644, UID: -1, GID: -1}, nil)
+	assert.Error(t, err)
+
+	content, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Equal(t, "original", string(content))
+	assertOnlyFile(t, dir, "config")
+}
+
+func TestWriteAtomicThroughSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "welfare")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "config")
+	link := path.Join(dir, "link")
+	require.Nil(t, ioutil.WriteFile(target, []byte("original"), 0644))
+	require.Nil(t, os.Symlink(target, link))
+
+	err = writeAtomic(link, strings.NewReader("hello"), permissions{FileMode: 0644, UID: -1, GID: -1}, nil)
+	require.Nil(t, err)
+
+	content, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	stat, err := os.Lstat(link)
+	require.Nil(t, err)
+	assert.True(t, stat.Mode()&os.ModeSymlink != 0)
+}
+
+func assertOnlyFile(t *testing.T, dir string, name string) {
+	infos, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, name, infos[0].Name())
+}
+
+type failingReader struct{}
+
+func (reader *failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
@@ -0,0 +1,182 @@
+package files
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backupOptions configures the backup of a file, before it is overwritten or removed
+type backupOptions struct {
+	// Backup keeps a timestamped copy of the file next to it, e.g. config.2026-10-18@12:00~
+	Backup bool
+	// BackupDir stores the timestamped copy in the directory instead of next to the file, it implies Backup
+	BackupDir string
+}
+
+func (options backupOptions) enabled() bool {
+	return options.Backup || options.BackupDir != ""
+}
+
+// backupPath returns an unused path for the backup of the file at path
+func (options backupOptions) backupPath(path string, now time.Time) (string, error) {
+	dir, name := filepath.Split(path)
+	if options.BackupDir != "" {
+		dir = options.BackupDir
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create backup directory %s", dir)
+		}
+	}
+
+	base := filepath.Join(dir, name+"."+now.Format("2006-01-02@15:04"))
+	candidate := base + "~"
+	for i := 1; ; i++ {
+		_, err := os.Lstat(candidate)
+		if os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", errors.Wrapf(err, "failed to stat backup %s", candidate)
+		}
+		candidate = base + "." + strconv.Itoa(i) + "~"
+	}
+}
+
+// backupFile copies the file to its backup path and returns the backup path. Mode, owner and modification time of the
+// file are kept. If backups are not enabled, backupFile does nothing and returns an empty path.
+func (options backupOptions) backupFile(file fileInfo) (string, error) {
+	if !options.enabled() || file.State != File {
+		return "", nil
+	}
+
+	path, err := options.backupPath(file.Path, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	err = copyBackupFile(file, path)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// backupAndRemove moves the file, link or directory to its backup path instead of removing it and returns the backup
+// path. If the backup path is on another filesystem, the file or the whole directory tree is copied to the backup path
+// and removed afterwards.
+func (options backupOptions) backupAndRemove(file fileInfo) (string, error) {
+	path, err := options.backupPath(file.Path, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(file.Path, path)
+	if linkErr, ok := err.(*os.LinkError); ok && linkErr.Err == syscall.EXDEV {
+		err = copyBackupTree(file.Path, path)
+		if err != nil {
+			return "", err
+		}
+
+		err = os.RemoveAll(file.Path)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to remove %s", file.Path)
+		}
+		return path, nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to move %s to backup %s", file.Path, path)
+	}
+	return path, nil
+}
+
+// copyBackupTree copies the file, link or directory tree at source to the backup path. Mode, owner and modification
+// time of every file are kept.
+func copyBackupTree(source string, path string) error {
+	return filepath.Walk(source, func(current string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk %s", current)
+		}
+
+		relative, err := filepath.Rel(source, current)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s relative to %s", current, source)
+		}
+		target := filepath.Join(path, relative)
+
+		file, err := collectLinkInfo(current)
+		if err != nil {
+			return err
+		}
+
+		switch file.State {
+		case Directory:
+			err = os.Mkdir(target, file.FileMode)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create backup directory %s", target)
+			}
+			err = os.Chmod(target, file.FileMode)
+			if err != nil {
+				return errors.Wrapf(err, "failed to change mode of backup directory %s", target)
+			}
+		case Link:
+			err = os.Symlink(file.LinkTarget, target)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create backup link %s", target)
+			}
+		default:
+			return copyBackupFile(file, target)
+		}
+
+		err = os.Lchown(target, file.UID, file.GID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to change owner of backup %s", target)
+		}
+		return nil
+	})
+}
+
+// copyBackupFile copies the regular file to the backup path, mode, owner and modification time are kept
+func copyBackupFile(file fileInfo, path string) error {
+	stat, err := os.Stat(file.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %s", file.Path)
+	}
+
+	source, err := os.Open(file.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", file.Path)
+	}
+	defer source.Close()
+
+	backup, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, file.FileMode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create backup %s", path)
+	}
+	defer backup.Close()
+
+	_, err = io.Copy(backup, source)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy %s to backup %s", file.Path, path)
+	}
+
+	err = backup.Chown(file.UID, file.GID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to change owner of backup %s", path)
+	}
+
+	err = backup.Chmod(file.FileMode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to change mode of backup %s", path)
+	}
+
+	err = os.Chtimes(path, stat.ModTime(), stat.ModTime())
+	if err != nil {
+		return errors.Wrapf(err, "failed to change modification time of backup %s", path)
+	}
+	return nil
+}
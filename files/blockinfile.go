@@ -0,0 +1,186 @@
+package files
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// DefaultMarker is the template of the lines, which surround a block. {mark} is replaced by BEGIN or END and {name} by
+// the name of the block.
+const DefaultMarker = "# {mark} welfare {name}"
+
+// NewBlockInFileModule creates a new BlockInFileModule, which ensures that the file at path contains the named block
+func NewBlockInFileModule(path string, name string, block string) *BlockInFileModule {
+	module := &BlockInFileModule{
+		Path:   path,
+		Name:   name,
+		Block:  block,
+		Marker: DefaultMarker,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// BlockInFileModule ensures that a block of lines between two marker lines is part of a file, the content outside of
+// the markers is left untouched. Mode and owner of an existing file are kept, unless they are declared by the module.
+type BlockInFileModule struct {
+	permissions
+	replaceOptions
+	Path string
+	// Name identifies the block, so that a file can contain more than one managed block
+	Name string
+	// Block is the content between the markers
+	Block string
+	// Marker is the template of the marker lines, with the placeholders {mark} and {name}
+	Marker string
+	// Absent removes the block together with its markers
+	Absent bool
+	// InsertAfter is a regular expression, a new block is inserted after the last matching line. EndOfFile or no match
+	// appends the block to the end of the file.
+	InsertAfter string
+	// InsertBefore is a regular expression, a new block is inserted before the last matching line. BeginningOfFile
+	// inserts the block at the beginning of the file.
+	InsertBefore string
+	// Create creates the file if it is missing, otherwise a missing file is an error
+	Create bool
+}
+
+func (module *BlockInFileModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the file, without touching it
+func (module *BlockInFileModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the block in the file, in check mode it only reports what would change
+func (module *BlockInFileModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *BlockInFileModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if module.Name == "" {
+		return false, errors.Errorf("block in %s requires a name", module.Path)
+	}
+	if !strings.Contains(module.Marker, "{mark}") {
+		return false, errors.Errorf("marker %s does not contain {mark}", module.Marker)
+	}
+	if module.InsertAfter != "" && module.InsertBefore != "" {
+		return false, errors.New("insert after and insert before are mutually exclusive")
+	}
+
+	insertAfter, err := compileOptional(module.InsertAfter, EndOfFile)
+	if err != nil {
+		return false, err
+	}
+	insertBefore, err := compileOptional(module.InsertBefore, BeginningOfFile)
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	missing := failMissing
+	if module.Absent {
+		missing = ignoreMissing
+	} else if module.Create {
+		missing = createMissing
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, missing, func(current string) (string, string, error) {
+		lines := contentLines(current)
+		begin, end, err := module.find(lines)
+		if err != nil {
+			return "", "", err
+		}
+
+		if module.Absent {
+			if begin < 0 {
+				return current, "", nil
+			}
+			lines = append(lines[:begin], lines[end+1:]...)
+			return joinLines(lines), "removed block " + module.Name + " from", nil
+		}
+
+		block := module.lines()
+		if begin < 0 {
+			index := insertIndex(lines, insertAfter, insertBefore, module.InsertBefore == BeginningOfFile)
+			lines = append(lines[:index], append(block, lines[index:]...)...)
+			return joinLines(lines), "inserted block " + module.Name + " into", nil
+		}
+
+		if equalLines(lines[begin:end+1], block) {
+			return current, "", nil
+		}
+		lines = append(lines[:begin], append(block, lines[end+1:]...)...)
+		return joinLines(lines), "changed block " + module.Name + " in", nil
+	}, check)
+}
+
+// find returns the index of the begin and the end marker, both are -1 if the lines do not contain the block
+func (module *BlockInFileModule) find(lines []string) (int, int, error) {
+	beginMarker, endMarker := module.marker("BEGIN"), module.marker("END")
+
+	begin, end := -1, -1
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if begin < 0 && line == beginMarker {
+			begin = i
+		} else if begin >= 0 && line == endMarker {
+			end = i
+			break
+		}
+	}
+
+	if begin >= 0 && end < 0 {
+		return -1, -1, errors.Errorf("%s contains %s without %s", module.Path, beginMarker, endMarker)
+	}
+	if begin < 0 {
+		for _, line := range lines {
+			if strings.TrimRight(line, " \t\r") == endMarker {
+				return -1, -1, errors.Errorf("%s contains %s without %s", module.Path, endMarker, beginMarker)
+			}
+		}
+	}
+	return begin, end, nil
+}
+
+// lines returns the block surrounded by the markers
+func (module *BlockInFileModule) lines() []string {
+	lines := []string{module.marker("BEGIN")}
+	lines = append(lines, contentLines(module.Block)...)
+	return append(lines, module.marker("END"))
+}
+
+func (module *BlockInFileModule) marker(mark string) string {
+	marker := strings.Replace(module.Marker, "{mark}", mark, -1)
+	return strings.Replace(marker, "{name}", module.Name, -1)
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
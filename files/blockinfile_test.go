@@ -0,0 +1,108 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hosts = `127.0.0.1 localhost
+::1 localhost ip6-localhost
+`
+
+func TestBlockInFileModule_ExecuteInsertsBlock(t *testing.T) {
+	target, cleanup := createFile(t, "hosts", hosts)
+	defer cleanup()
+
+	module := files.NewBlockInFileModule(target, "scm", "10.0.0.2 scm\n10.0.0.3 ci\n")
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "inserted block scm into "+target, result.Message)
+	assertContent(t, hosts+"# BEGIN welfare scm\n10.0.0.2 scm\n10.0.0.3 ci\n# END welfare scm\n", target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestBlockInFileModule_ExecuteChangesBlock(t *testing.T) {
+	target, cleanup := createFile(t, "hosts", "# BEGIN welfare scm\n10.0.0.2 scm\n# END welfare scm\n"+hosts)
+	defer cleanup()
+
+	module := files.NewBlockInFileModule(target, "scm", "10.0.0.4 scm")
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "changed block scm in "+target, result.Message)
+	assert.Contains(t, result.Diff, "-10.0.0.2 scm\n+10.0.0.4 scm\n")
+	assertContent(t, "# BEGIN welfare scm\n10.0.0.4 scm\n# END welfare scm\n"+hosts, target)
+}
+
+func TestBlockInFileModule_ExecuteWithInsertPositionAndMarker(t *testing.T) {
+	target, cleanup := createFile(t, "hosts", hosts)
+	defer cleanup()
+
+	module := files.NewBlockInFileModule(target, "first", "10.0.0.1 first")
+	module.InsertBefore = files.BeginningOfFile
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	module = files.NewBlockInFileModule(target, "ipv4", "10.0.0.5 web")
+	module.Marker = "## {mark} {name} ##"
+	module.InsertAfter = "^127"
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, "# BEGIN welfare first\n10.0.0.1 first\n# END welfare first\n127.0.0.1 localhost\n"+
+		"## BEGIN ipv4 ##\n10.0.0.5 web\n## END ipv4 ##\n::1 localhost ip6-localhost\n", target)
+}
+
+func TestBlockInFileModule_ExecuteWithAbsent(t *testing.T) {
+	target, cleanup := createFile(t, "hosts", "127.0.0.1 localhost\n# BEGIN welfare scm\n10.0.0.2 scm\n# END welfare scm\n::1 localhost ip6-localhost\n")
+	defer cleanup()
+
+	module := files.NewBlockInFileModule(target, "scm", "")
+	module.Absent = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "removed block scm from "+target, result.Message)
+	assertContent(t, hosts, target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestBlockInFileModule_ExecuteWithIncompleteBlock(t *testing.T) {
+	target, cleanup := createFile(t, "hosts", hosts+"# BEGIN welfare scm\n10.0.0.2 scm\n")
+	defer cleanup()
+
+	module := files.NewBlockInFileModule(target, "scm", "10.0.0.2 scm")
+	_, err := module.Execute(context.Background(), false)
+	assert.EqualError(t, err, target+" contains # BEGIN welfare scm without # END welfare scm")
+}
+
+func TestBlockInFileModule_ExecuteWithCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockinfile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, ".bashrc")
+	module := files.NewBlockInFileModule(target, "path", "export PATH=$PATH:/opt/scm/bin")
+	module.Create = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "created file "+target, result.Message)
+	assertContent(t, "# BEGIN welfare path\nexport PATH=$PATH:/opt/scm/bin\n# END welfare path\n", target)
+}
@@ -1,10 +1,13 @@
 package files
 
 import (
+	"context"
 	"io"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewCopyModule creates a new CopyModule with the given source and target file
@@ -19,20 +22,61 @@ func NewCopyModule(source string, target string) *CopyModule {
 	return module
 }
 
-// CopyModule ensures that the target is an exact copy of the source file
+// CopyModule ensures that the target is an exact copy of the source file. If the source is a directory, the target
+// becomes a copy of the whole directory tree. The mode of the module applies only to the files of the tree, the
+// directories keep the mode of the source.
 type CopyModule struct {
 	permissions
+	replaceOptions
 	Source string
 	Target string
+	// PreserveModTime sets the modification time of the target to the modification time of the source
+	PreserveModTime bool
+	// Delete removes files of a target directory, which are not part of the source directory
+	Delete bool
+	// Include limits the copied files of a directory to the files which match one of the glob patterns. Patterns
+	// without a slash are matched against the name of the file, others against the path relative to the source.
+	Include []string
+	// Exclude skips files and directories which match one of the glob patterns, excluded files are never deleted
+	Exclude []string
 }
 
 func (module *CopyModule) Run() (bool, error) {
-	expected, err := collectAndMergeFileInfo(module.Source, module.permissions)
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the target, without touching it
+func (module *CopyModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures that the target is a copy of the source, in check mode it only reports what would change
+func (module *CopyModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *CopyModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	source, err := collectFileInfo(module.Source)
 	if err != nil {
 		return false, err
 	}
 
-	if expected.State != File {
+	if source.State == Directory {
+		return module.copyDirectory(ctx, result, source, perms, check)
+	}
+
+	if source.State != File {
 		return false, errors.Errorf("expected file %s seams to be not a file", module.Source)
 	}
 
@@ -40,29 +84,80 @@ func (module *CopyModule) Run() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	result.Before = target.state()
+
+	expected, err := expectedFileInfo(source, target, perms)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := ensureCopy(ctx, result, expected, target, module.replaceOptions, module.PreserveModTime, check)
+	if err != nil {
+		return false, err
+	}
+
+	result.After = expected.state()
+	if changed {
+		result.Message = "copied " + expected.Path + " to " + target.Path
+	}
 
-	return ensureCopy(expected, target)
+	return changed, nil
 }
 
-func ensureCopy(expected, target fileInfo) (bool, error) {
+// expectedFileInfo merges the permissions into the source. A symbolic mode is applied to the mode of the target, or to
+// the mode of the source if the target does not exist.
+func expectedFileInfo(source, target fileInfo, perms permissions) (fileInfo, error) {
+	perms, err := perms.withMode(currentMode(target, source.FileMode), false)
+	if err != nil {
+		return source, err
+	}
+	return mergeFilePermissions(source, perms), nil
+}
+
+// ensureCopy ensures that target is a copy of expected and stores a diff of the content change in the result. The
+// copy is validated and the replaced content of the target is backed up, before the target is overwritten. If
+// preserveModTime is true, the target gets the modification time of expected.
+func ensureCopy(ctx context.Context, result *welfare.Result, expected, target fileInfo, options replaceOptions, preserveModTime bool, check bool) (bool, error) {
 	contentChanged := false
 	if target.State == Absent || expected.Checksum != target.Checksum {
-		err := copy(expected, target)
+		var err error
+		result.Diff, err = copyDiff(expected, target)
+		if err != nil {
+			return false, err
+		}
+
+		if check {
+			return true, nil
+		}
+
+		err = copy(ctx, result, expected, target, options)
 		if err != nil {
 			return false, err
 		}
 		contentChanged = true
 	}
 
-	permissionsChanged, err := ensurePermissions(expected.permissions, target)
+	permissionsChanged, err := ensurePermissions(expected.permissions, target, check)
 	if err != nil {
 		return false, err
 	}
 
-	return contentChanged || permissionsChanged, nil
+	modTimeChanged := false
+	if preserveModTime && (contentChanged || !expected.ModTime.Equal(target.ModTime)) {
+		if !check {
+			err = os.Chtimes(target.Path, time.Now(), expected.ModTime)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to change modification time of %s", target.Path)
+			}
+		}
+		modTimeChanged = true
+	}
+
+	return contentChanged || permissionsChanged || modTimeChanged, nil
 }
 
-func copy(expected, target fileInfo) error {
+// copy replaces the target atomically with a copy of expected
+func copy(ctx context.Context, result *welfare.Result, expected, target fileInfo, options replaceOptions) error {
 	sourcePath := expected.Path
 	targetPath := target.Path
 
@@ -70,24 +165,25 @@ func copy(expected, target fileInfo) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to open sourceFile %s", sourcePath)
 	}
+	defer sourceFile.Close()
 
-	var targetFile *os.File
-	if target.State == Absent {
-		targetFile, err = os.Create(targetPath)
-		if err != nil {
-			return errors.Wrapf(err, "failed to create file at %s", targetPath)
-		}
-	} else {
-		targetFile, err = os.OpenFile(targetPath, os.O_TRUNC|os.O_WRONLY, os.ModeAppend)
-		if err != nil {
-			return errors.Wrapf(err, "failed to open file at %s", targetPath)
-		}
-	}
-
-	_, err = io.Copy(targetFile, sourceFile)
+	err = options.replace(ctx, result, target, &contextReader{ctx, sourceFile}, expected.permissions)
 	if err != nil {
 		return errors.Wrapf(err, "failed to copy %s to %s", sourcePath, targetPath)
 	}
 
 	return nil
 }
+
+// contextReader stops reading as soon as the context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (reader *contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(p)
+}
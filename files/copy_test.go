@@ -1,11 +1,14 @@
 package files_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
 
+	"github.com/sdorra/welfare"
 	"github.com/sdorra/welfare/files"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -134,3 +137,247 @@ func TestCopyModule_RunWithoutSource(t *testing.T) {
 	_, err = copy.Run()
 	assert.Error(t, err)
 }
+
+func TestCopyModule_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	err = ioutil.WriteFile(source, []byte("a"), 0644)
+	require.Nil(t, err)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("b"), 0755)
+	require.Nil(t, err)
+
+	copy := files.NewCopyModule(source, target)
+
+	changed, err := copy.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	bytes, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", string(bytes))
+
+	stat, err := os.Stat(target)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), stat.Mode())
+}
+
+func TestCopyModule_Execute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	err = ioutil.WriteFile(source, []byte("a\n"), 0644)
+	require.Nil(t, err)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("b\n"), 0644)
+	require.Nil(t, err)
+
+	copy := files.NewCopyModule(source, target)
+
+	result, err := copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "--- "+target+"\n+++ "+target+"\n@@ -1 +1 @@\n-b\n+a\n", result.Diff)
+	assert.NotEqual(t, result.Before.Checksum, result.After.Checksum)
+}
+
+func TestCopyModule_ExecuteWithBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	err = ioutil.WriteFile(source, []byte("a\n"), 0644)
+	require.Nil(t, err)
+
+	target := path.Join(dir, "target")
+
+	copy := files.NewCopyModule(source, target)
+	copy.Backup = true
+
+	result, err := copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Backup)
+
+	err = ioutil.WriteFile(source, []byte("b\n"), 0644)
+	require.Nil(t, err)
+
+	result, err = copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, result.Backup)
+
+	bytes, err := ioutil.ReadFile(result.Backup)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\n", string(bytes))
+}
+
+func TestCopyModule_ExecuteWithPreserveModTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	modTime := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	source := path.Join(dir, "source")
+	err = ioutil.WriteFile(source, []byte("a\n"), 0644)
+	require.Nil(t, err)
+	err = os.Chtimes(source, modTime, modTime)
+	require.Nil(t, err)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("a\n"), 0644)
+	require.Nil(t, err)
+
+	copy := files.NewCopyModule(source, target)
+	copy.PreserveModTime = true
+
+	result, err := copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.True(t, modTime.Equal(stat.ModTime()))
+
+	result, err = copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestCopyModule_ExecuteWithDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	writeTree(t, source, map[string]string{
+		"index.html":     "<h1>welfare</h1>",
+		"css/style.css":  "h1 {}",
+		"css/.gitignore": "*.map",
+	})
+	require.Nil(t, os.Symlink("index.html", path.Join(source, "default.html")))
+
+	target := path.Join(dir, "target")
+	copy := files.NewCopyModule(source, target)
+
+	result, err := copy.Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+
+	result, err = copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Len(t, result.ChangedPaths, 6)
+	assert.Contains(t, result.Diff, "+h1 {}")
+
+	bytes, err := ioutil.ReadFile(path.Join(target, "css", "style.css"))
+	assert.Nil(t, err)
+	assert.Equal(t, "h1 {}", string(bytes))
+
+	link, err := os.Readlink(path.Join(target, "default.html"))
+	assert.Nil(t, err)
+	assert.Equal(t, "index.html", link)
+
+	err = ioutil.WriteFile(path.Join(source, "css", "style.css"), []byte("h1 {color: red}"), 0644)
+	require.Nil(t, err)
+
+	result, err = copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(target, "css", "style.css")}, result.ChangedPaths)
+
+	result, err = copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assert.Empty(t, result.ChangedPaths)
+}
+
+func TestCopyModule_ExecuteWithDirectoryAndDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	writeTree(t, source, map[string]string{
+		"index.html":  "<h1>welfare</h1>",
+		"notes.txt":   "not deployed",
+		".git/config": "[core]",
+	})
+
+	target := path.Join(dir, "target")
+	writeTree(t, target, map[string]string{
+		"index.html":     "<h1>welfare</h1>",
+		"old.html":       "<h1>old</h1>",
+		"old/index.html": "<h1>old</h1>",
+		"upload.txt":     "kept, because it is not included",
+		".git/HEAD":      "kept, because it is excluded",
+	})
+
+	copy := files.NewCopyModule(source, target)
+	copy.Delete = true
+	copy.Include = []string{"*.html"}
+	copy.Exclude = []string{".git"}
+
+	result, err := copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, []string{path.Join(target, "old"), path.Join(target, "old.html")}, result.ChangedPaths)
+
+	for _, name := range []string{"index.html", "upload.txt", ".git/HEAD"} {
+		_, err = os.Stat(path.Join(target, name))
+		assert.Nil(t, err)
+	}
+
+	for _, name := range []string{"old", "old.html", "notes.txt", ".git/config"} {
+		_, err = os.Stat(path.Join(target, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
+
+func TestCopyModule_ExecuteWithDirectoryReplacesLinkOfTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	writeTree(t, source, map[string]string{"a": "new"})
+
+	outside := path.Join(dir, "outside")
+	require.Nil(t, ioutil.WriteFile(outside, []byte("old"), 0644))
+
+	target := path.Join(dir, "target")
+	require.Nil(t, os.Mkdir(target, 0755))
+	require.Nil(t, os.Symlink("../outside", path.Join(target, "a")))
+
+	copy := files.NewCopyModule(source, target)
+	result, err := copy.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(target, "a")}, result.ChangedPaths)
+
+	stat, err := os.Lstat(path.Join(target, "a"))
+	require.Nil(t, err)
+	assert.True(t, stat.Mode().IsRegular())
+
+	bytes, err := ioutil.ReadFile(path.Join(target, "a"))
+	assert.Nil(t, err)
+	assert.Equal(t, "new", string(bytes))
+
+	bytes, err = ioutil.ReadFile(outside)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", string(bytes))
+}
+
+func writeTree(t *testing.T, root string, tree map[string]string) {
+	for name, content := range tree {
+		filePath := path.Join(root, name)
+		require.Nil(t, os.MkdirAll(path.Dir(filePath), 0755))
+		require.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	}
+}
@@ -0,0 +1,256 @@
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxDiffSize is the maximum size in bytes of content, for which a diff is created
+	maxDiffSize = 64 * 1024
+	// maxDiffCells limits the size of the lcs table, larger changes are rendered as a single replacement
+	maxDiffCells = 4 * 1024 * 1024
+	// diffContext is the amount of unchanged lines around each change
+	diffContext = 3
+	// binarySniffLength is the amount of bytes which are inspected to detect binary content
+	binarySniffLength = 8000
+)
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// contentDiff creates a unified diff between the current content of the target and the desired content
+func contentDiff(target fileInfo, desired []byte) (string, error) {
+	if target.Size > maxDiffSize || len(desired) > maxDiffSize {
+		return diffTooLarge(target.Path), nil
+	}
+
+	current, err := readCurrent(target)
+	if err != nil {
+		return "", err
+	}
+
+	return createDiff(target, current, desired), nil
+}
+
+// copyDiff creates a unified diff between the current content of the target and the content of the source file
+func copyDiff(source, target fileInfo) (string, error) {
+	if target.Size > maxDiffSize || source.Size > maxDiffSize {
+		return diffTooLarge(target.Path), nil
+	}
+
+	desired, err := ioutil.ReadFile(source.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s for diff", source.Path)
+	}
+
+	current, err := readCurrent(target)
+	if err != nil {
+		return "", err
+	}
+
+	return createDiff(target, current, desired), nil
+}
+
+func readCurrent(target fileInfo) ([]byte, error) {
+	if target.State == Absent {
+		return []byte{}, nil
+	}
+
+	current, err := ioutil.ReadFile(target.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s for diff", target.Path)
+	}
+	return current, nil
+}
+
+func diffTooLarge(path string) string {
+	return fmt.Sprintf("diff of %s skipped, content exceeds %d bytes\n", path, maxDiffSize)
+}
+
+func createDiff(target fileInfo, current, desired []byte) string {
+	from := target.Path
+	if target.State == Absent {
+		from = "/dev/null"
+	}
+
+	if isBinary(current) || isBinary(desired) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", from, target.Path)
+	}
+
+	ops := diffLines(splitLines(current), splitLines(desired))
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "--- %s\n+++ %s\n", from, target.Path)
+	writeHunks(&buffer, ops)
+	return buffer.String()
+}
+
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+func splitLines(content []byte) []string {
+	lines := []string{}
+	for len(content) > 0 {
+		index := bytes.IndexByte(content, '\n')
+		if index < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+		lines = append(lines, string(content[:index+1]))
+		content = content[index+1:]
+	}
+	return lines
+}
+
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := []diffOp{}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	ops := []diffOp{}
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i*width+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	lcs := make([]int, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		} else {
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func writeHunks(buffer *bytes.Buffer, ops []diffOp) {
+	changes := []int{}
+	for index, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, index)
+		}
+	}
+
+	for c := 0; c < len(changes); {
+		start := changes[c] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		last := changes[c]
+		c++
+		for c < len(changes) && changes[c]-last <= 2*diffContext {
+			last = changes[c]
+			c++
+		}
+		end := last + diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(buffer, ops, start, end)
+	}
+}
+
+func writeHunk(buffer *bytes.Buffer, ops []diffOp, start, end int) {
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			fromLine++
+		}
+		if op.kind != '-' {
+			toLine++
+		}
+	}
+
+	fromCount, toCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+
+	fmt.Fprintf(buffer, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, op := range ops[start:end] {
+		buffer.WriteByte(op.kind)
+		buffer.WriteString(op.line)
+		if len(op.line) == 0 || op.line[len(op.line)-1] != '\n' {
+			buffer.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
@@ -0,0 +1,80 @@
+package files
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDiff(t *testing.T) {
+	target := fileInfo{Path: "/etc/welfare/config", State: File}
+
+	current := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	desired := "one\ntwo\nthree\nfour\nfive\nsix\nseven\nacht\nnine\nten\n"
+
+	expected := `--- /etc/welfare/config
++++ /etc/welfare/config
+@@ -5,6 +5,6 @@
+ five
+ six
+ seven
+-eight
++acht
+ nine
+ ten
+`
+	assert.Equal(t, expected, createDiff(target, []byte(current), []byte(desired)))
+}
+
+func TestCreateDiffWithMultipleHunks(t *testing.T) {
+	target := fileInfo{Path: "config", State: File}
+
+	current := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	desired := "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\n"
+
+	expected := `--- config
++++ config
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -9,4 +9,4 @@
+ i
+ j
+ k
+-l
++L
+`
+	assert.Equal(t, expected, createDiff(target, []byte(current), []byte(desired)))
+}
+
+func TestCreateDiffWithAbsentTarget(t *testing.T) {
+	target := fileInfo{Path: "config", State: Absent}
+
+	expected := `--- /dev/null
++++ config
+@@ -0,0 +1,2 @@
++one
++two
+\ No newline at end of file
+`
+	assert.Equal(t, expected, createDiff(target, []byte{}, []byte("one\ntwo")))
+}
+
+func TestCreateDiffWithBinaryContent(t *testing.T) {
+	target := fileInfo{Path: "image", State: File}
+
+	diff := createDiff(target, []byte("a\x00b"), []byte("c"))
+	assert.Equal(t, "Binary files image and image differ\n", diff)
+}
+
+func TestContentDiffWithLargeContent(t *testing.T) {
+	target := fileInfo{Path: "large", State: Absent}
+
+	diff, err := contentDiff(target, []byte(strings.Repeat("a", maxDiffSize+1)))
+	assert.Nil(t, err)
+	assert.Contains(t, diff, "diff of large skipped")
+}
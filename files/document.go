@@ -0,0 +1,277 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+const (
+	// JSONFormat is the format of JSON documents
+	JSONFormat = "json"
+	// YAMLFormat is the format of YAML documents
+	YAMLFormat = "yaml"
+	// TOMLFormat is the format of TOML documents
+	TOMLFormat = "toml"
+)
+
+// NewDocumentModule creates a new DocumentModule, which sets the value of the key in the document at path
+func NewDocumentModule(path string, key string, value interface{}) *DocumentModule {
+	module := &DocumentModule{
+		Path:  path,
+		Key:   key,
+		Value: value,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// DocumentModule ensures a single value of a JSON, YAML or TOML document, every other value of the document is kept.
+// The order of the keys is kept for JSON and YAML documents and the comments of YAML documents are kept. TOML documents
+// are written with sorted keys and without comments. The document is only written, if the value differs.
+type DocumentModule struct {
+	permissions
+	replaceOptions
+	Path string
+	// Format is one of JSONFormat, YAMLFormat or TOMLFormat. If it is empty, the format is detected by the extension of
+	// the path.
+	Format string
+	// Key is the path of the value within the document, the names of nested keys are separated by dots, e.g.
+	// server.tls.enabled. A number selects an item of a list. Missing maps are created.
+	Key   string
+	Value interface{}
+	// Absent removes the key from the document
+	Absent bool
+	// Create creates the document if it is missing, otherwise a missing document is an error
+	Create bool
+}
+
+func (module *DocumentModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the document, without touching it
+func (module *DocumentModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the value in the document, in check mode it only reports what would change
+func (module *DocumentModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *DocumentModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	keys, err := splitKey(module.Key)
+	if err != nil {
+		return false, err
+	}
+
+	format, err := module.format()
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	missing := failMissing
+	if module.Absent {
+		missing = ignoreMissing
+	} else if module.Create {
+		missing = createMissing
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, missing, func(current string) (string, string, error) {
+		var content string
+		var changed bool
+		var err error
+		if format == TOMLFormat {
+			content, changed, err = module.editTOML(current, keys)
+		} else {
+			content, changed, err = module.editNode(current, keys, format)
+		}
+		if err != nil || !changed {
+			return current, "", err
+		}
+		if module.Absent {
+			return content, "removed " + module.Key + " from", nil
+		}
+		return content, "set " + module.Key + " in", nil
+	}, check)
+}
+
+// format returns the declared format or the format, which is detected by the extension of the path
+func (module *DocumentModule) format() (string, error) {
+	switch module.Format {
+	case JSONFormat, YAMLFormat, TOMLFormat:
+		return module.Format, nil
+	case "":
+	default:
+		return "", errors.Errorf("unknown document format %s", module.Format)
+	}
+
+	switch strings.ToLower(filepath.Ext(module.Path)) {
+	case ".json":
+		return JSONFormat, nil
+	case ".yaml", ".yml":
+		return YAMLFormat, nil
+	case ".toml":
+		return TOMLFormat, nil
+	default:
+		return "", errors.Errorf("could not detect format of %s, please declare the format", module.Path)
+	}
+}
+
+func splitKey(key string) ([]string, error) {
+	keys := strings.Split(key, ".")
+	for _, name := range keys {
+		if name == "" {
+			return nil, errors.Errorf("invalid key %s, the names of the key must not be empty", key)
+		}
+	}
+	return keys, nil
+}
+
+// editTOML sets or removes the value of a TOML document and encodes the whole document again
+func (module *DocumentModule) editTOML(current string, keys []string) (string, bool, error) {
+	document := map[string]interface{}{}
+	_, err := toml.Decode(current, &document)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to parse %s", module.Path)
+	}
+
+	var changed bool
+	if module.Absent {
+		changed, err = deleteValue(document, keys)
+	} else if module.Value == nil {
+		// TOML has no null, a key is removed with Absent instead
+		return "", false, errors.Errorf("value of %s in %s must not be null, TOML has no null values", module.Key, module.Path)
+	} else {
+		var value interface{}
+		value, err = normalizeTOML(module.Value)
+		if err != nil {
+			return "", false, err
+		}
+		changed, err = setValue(document, keys, value)
+	}
+	if err != nil || !changed {
+		return "", false, errors.Wrapf(err, "failed to edit %s of %s", module.Key, module.Path)
+	}
+
+	buffer := bytes.Buffer{}
+	encoder := toml.NewEncoder(&buffer)
+	encoder.Indent = ""
+	err = encoder.Encode(document)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to encode %s", module.Path)
+	}
+	return buffer.String(), true, nil
+}
+
+// normalizeTOML converts the value to the types, which are returned by the TOML decoder, e.g. int to int64
+func normalizeTOML(value interface{}) (interface{}, error) {
+	buffer := bytes.Buffer{}
+	err := toml.NewEncoder(&buffer).Encode(map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, errors.Wrapf(err, "value %v can not be stored in TOML", value)
+	}
+
+	decoded := map[string]interface{}{}
+	_, err = toml.Decode(buffer.String(), &decoded)
+	if err != nil {
+		return nil, errors.Wrapf(err, "value %v can not be stored in TOML", value)
+	}
+	return decoded["value"], nil
+}
+
+// setValue sets the value of the key within the decoded document, missing maps are created
+func setValue(document interface{}, keys []string, value interface{}) (bool, error) {
+	if mapping, ok := document.(map[string]interface{}); ok {
+		child, exists := mapping[keys[0]]
+		if len(keys) == 1 {
+			if exists && reflect.DeepEqual(child, value) {
+				return false, nil
+			}
+			mapping[keys[0]] = value
+			return true, nil
+		}
+		if !exists {
+			child = map[string]interface{}{}
+			mapping[keys[0]] = child
+		}
+		return setValue(child, keys[1:], value)
+	}
+
+	list, index, err := listItem(document, keys[0])
+	if err != nil {
+		return false, err
+	}
+	item := list.Index(index)
+	if len(keys) == 1 {
+		if reflect.DeepEqual(item.Interface(), value) {
+			return false, nil
+		}
+		if !reflect.ValueOf(value).Type().AssignableTo(item.Type()) {
+			return false, errors.Errorf("value of %s must be of type %s", keys[0], item.Type())
+		}
+		item.Set(reflect.ValueOf(value))
+		return true, nil
+	}
+	return setValue(item.Interface(), keys[1:], value)
+}
+
+// deleteValue removes the key from the decoded document, a missing key is not an error
+func deleteValue(document interface{}, keys []string) (bool, error) {
+	if mapping, ok := document.(map[string]interface{}); ok {
+		child, exists := mapping[keys[0]]
+		if !exists {
+			return false, nil
+		}
+		if len(keys) == 1 {
+			delete(mapping, keys[0])
+			return true, nil
+		}
+		return deleteValue(child, keys[1:])
+	}
+
+	list, index, err := listItem(document, keys[0])
+	if err != nil {
+		return false, err
+	}
+	if len(keys) == 1 {
+		return false, errors.Errorf("items of lists can not be removed from TOML documents")
+	}
+	return deleteValue(list.Index(index).Interface(), keys[1:])
+}
+
+// listItem returns the list and the index of the item, which is selected by the key
+func listItem(document interface{}, key string) (reflect.Value, int, error) {
+	list := reflect.ValueOf(document)
+	if list.Kind() != reflect.Slice {
+		return list, 0, errors.Errorf("%s is neither part of a map nor of a list", key)
+	}
+
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || index >= list.Len() {
+		return list, 0, errors.Errorf("%s is not an index of a list with %d items", key, list.Len())
+	}
+	return list, index, nil
+}
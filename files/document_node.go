@@ -0,0 +1,335 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// editNode sets or removes the value of a JSON or YAML document. Both formats are edited as tree of yaml nodes, so that
+// the order of the keys and the comments of YAML documents are kept.
+func (module *DocumentModule) editNode(current string, keys []string, format string) (string, bool, error) {
+	var document *yaml.Node
+	var err error
+	if format == JSONFormat {
+		document, err = parseJSONNode(current)
+	} else {
+		document, err = parseYAMLNode(current)
+	}
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to parse %s", module.Path)
+	}
+
+	var changed bool
+	if module.Absent {
+		changed, err = deleteNode(document.Content[0], keys)
+	} else {
+		value := &yaml.Node{}
+		err = value.Encode(module.Value)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "failed to encode value of %s", module.Key)
+		}
+		changed, err = setNode(document.Content[0], keys, value)
+	}
+	if err != nil || !changed {
+		return "", false, errors.Wrapf(err, "failed to edit %s of %s", module.Key, module.Path)
+	}
+
+	buffer := bytes.Buffer{}
+	if format == JSONFormat {
+		err = writeJSONNode(&buffer, document.Content[0], documentIndentation(current, "\t "), 0)
+		buffer.WriteString("\n")
+	} else {
+		indent := len(documentIndentation(current, " "))
+		if indent < 2 {
+			indent = 2
+		}
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(indent)
+		err = encoder.Encode(document)
+		if err == nil {
+			err = encoder.Close()
+		}
+	}
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to encode %s", module.Path)
+	}
+	return buffer.String(), true, nil
+}
+
+// parseYAMLNode parses the YAML document, an empty document is parsed as empty map
+func parseYAMLNode(content string) (*yaml.Node, error) {
+	document := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(content), document)
+	if err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		headComment := document.HeadComment
+		document = emptyDocument()
+		document.HeadComment = headComment
+	}
+	return document, nil
+}
+
+// parseJSONNode parses the JSON document into a tree of yaml nodes, which keeps the order of the keys. An empty
+// document is parsed as empty map.
+func parseJSONNode(content string) (*yaml.Node, error) {
+	if strings.TrimSpace(content) == "" {
+		return emptyDocument(), nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	root, err := decodeJSONNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected content after the end of the document")
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, nil
+}
+
+func decodeJSONNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := decodeJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		// consume the closing delimiter
+		_, err = decoder.Token()
+		return node, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+func emptyDocument() *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// setNode replaces the node of the key with the value, missing maps are created. The comments of a replaced node are
+// moved to the value.
+func setNode(parent *yaml.Node, keys []string, value *yaml.Node) (bool, error) {
+	parent = resolveAlias(parent)
+	if parent.Kind == yaml.MappingNode {
+		for i := 0; i < len(parent.Content); i += 2 {
+			if parent.Content[i].Value != keys[0] {
+				continue
+			}
+			if len(keys) == 1 {
+				return replaceNode(parent.Content, i+1, value)
+			}
+			return setNode(parent.Content[i+1], keys[1:], value)
+		}
+
+		child := value
+		for i := len(keys) - 1; i > 0; i-- {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{keyNode(keys[i]), child}}
+		}
+		parent.Content = append(parent.Content, keyNode(keys[0]), child)
+		return true, nil
+	}
+
+	index, err := nodeIndex(parent, keys[0])
+	if err != nil {
+		return false, err
+	}
+	if len(keys) == 1 {
+		return replaceNode(parent.Content, index, value)
+	}
+	return setNode(parent.Content[index], keys[1:], value)
+}
+
+// deleteNode removes the key and its value, a missing key is not an error
+func deleteNode(parent *yaml.Node, keys []string) (bool, error) {
+	parent = resolveAlias(parent)
+	if parent.Kind == yaml.MappingNode {
+		for i := 0; i < len(parent.Content); i += 2 {
+			if parent.Content[i].Value != keys[0] {
+				continue
+			}
+			if len(keys) == 1 {
+				parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+				return true, nil
+			}
+			return deleteNode(parent.Content[i+1], keys[1:])
+		}
+		return false, nil
+	}
+
+	index, err := nodeIndex(parent, keys[0])
+	if err != nil {
+		return false, err
+	}
+	if len(keys) == 1 {
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		return true, nil
+	}
+	return deleteNode(parent.Content[index], keys[1:])
+}
+
+func replaceNode(nodes []*yaml.Node, index int, value *yaml.Node) (bool, error) {
+	equal, err := equalNodes(nodes[index], value)
+	if err != nil || equal {
+		return false, err
+	}
+
+	value.HeadComment = nodes[index].HeadComment
+	value.LineComment = nodes[index].LineComment
+	value.FootComment = nodes[index].FootComment
+	nodes[index] = value
+	return true, nil
+}
+
+// equalNodes compares the values of both nodes, regardless of their style and comments
+func equalNodes(a, b *yaml.Node) (bool, error) {
+	var first, second interface{}
+	err := a.Decode(&first)
+	if err != nil {
+		return false, err
+	}
+	err = b.Decode(&second)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(first, second), nil
+}
+
+// nodeIndex returns the index of the item of the list, which is selected by the key
+func nodeIndex(list *yaml.Node, key string) (int, error) {
+	if list.Kind != yaml.SequenceNode {
+		return 0, errors.Errorf("%s is neither part of a map nor of a list", key)
+	}
+
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || index >= len(list.Content) {
+		return 0, errors.Errorf("%s is not an index of a list with %d items", key, len(list.Content))
+	}
+	return index, nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func keyNode(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+}
+
+// documentIndentation returns the indentation of the first indented line of the document, it defaults to two spaces
+func documentIndentation(content string, whitespace string) string {
+	for _, line := range contentLines(content) {
+		trimmed := strings.TrimLeft(line, whitespace)
+		if trimmed != "" && trimmed != line && !strings.HasPrefix(trimmed, "#") {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// writeJSONNode writes the node as indented JSON, the keys of maps are written in the order of the node
+func writeJSONNode(writer *bytes.Buffer, node *yaml.Node, indent string, depth int) error {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		opening, closing, step := "[", "]", 1
+		if node.Kind == yaml.MappingNode {
+			opening, closing, step = "{", "}", 2
+		}
+		writer.WriteString(opening)
+		for i := 0; i < len(node.Content); i += step {
+			if i > 0 {
+				writer.WriteString(",")
+			}
+			writer.WriteString("\n" + strings.Repeat(indent, depth+1))
+			if node.Kind == yaml.MappingNode {
+				err := writeJSONValue(writer, node.Content[i].Value)
+				if err != nil {
+					return err
+				}
+				writer.WriteString(": ")
+			}
+			err := writeJSONNode(writer, node.Content[i+step-1], indent, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		if len(node.Content) > 0 {
+			writer.WriteString("\n" + strings.Repeat(indent, depth))
+		}
+		writer.WriteString(closing)
+		return nil
+	case yaml.ScalarNode:
+		tag := node.ShortTag()
+		if (tag == "!!int" || tag == "!!float") && json.Valid([]byte(node.Value)) {
+			writer.WriteString(node.Value)
+			return nil
+		}
+		if tag == "!!str" {
+			return writeJSONValue(writer, node.Value)
+		}
+
+		var value interface{}
+		err := node.Decode(&value)
+		if err != nil {
+			return err
+		}
+		return writeJSONValue(writer, value)
+	default:
+		return errors.Errorf("unsupported node at line %d", node.Line)
+	}
+}
+
+func writeJSONValue(writer *bytes.Buffer, value interface{}) error {
+	encoded := bytes.Buffer{}
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	if err != nil {
+		return err
+	}
+	writer.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,193 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentModule_ExecuteWithJSON(t *testing.T) {
+	target, cleanup := createFile(t, "daemon.json", `{
+    "storage-driver": "overlay2",
+    "log-opts": {
+        "max-size": "10m"
+    },
+    "dns": ["10.0.0.2"]
+}
+`)
+	defer cleanup()
+
+	module := files.NewDocumentModule(target, "log-opts.max-file", "3")
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "set log-opts.max-file in "+target, result.Message)
+	assertContent(t, `{
+    "storage-driver": "overlay2",
+    "log-opts": {
+        "max-size": "10m",
+        "max-file": "3"
+    },
+    "dns": [
+        "10.0.0.2"
+    ]
+}
+`, target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+
+	module = files.NewDocumentModule(target, "live-restore", true)
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewDocumentModule(target, "storage-driver", nil)
+	module.Absent = true
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewDocumentModule(target, "dns.0", "10.0.0.3")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `{
+    "log-opts": {
+        "max-size": "10m",
+        "max-file": "3"
+    },
+    "dns": [
+        "10.0.0.3"
+    ],
+    "live-restore": true
+}
+`, target)
+}
+
+func TestDocumentModule_ExecuteWithYAMLKeepsComments(t *testing.T) {
+	target, cleanup := createFile(t, "grafana.yaml", `# grafana datasources
+apiVersion: 1
+datasources:
+  - name: prometheus # the default datasource
+    url: http://localhost:9090
+    isDefault: true
+`)
+	defer cleanup()
+
+	module := files.NewDocumentModule(target, "datasources.0.url", "http://prometheus:9090")
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assertContent(t, `# grafana datasources
+apiVersion: 1
+datasources:
+  - name: prometheus # the default datasource
+    url: http://prometheus:9090
+    isDefault: true
+`, target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+
+	module = files.NewDocumentModule(target, "server.tls.enabled", true)
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `# grafana datasources
+apiVersion: 1
+datasources:
+  - name: prometheus # the default datasource
+    url: http://prometheus:9090
+    isDefault: true
+server:
+  tls:
+    enabled: true
+`, target)
+}
+
+func TestDocumentModule_ExecuteWithTOML(t *testing.T) {
+	target, cleanup := createFile(t, "config.toml", `title = "welfare"
+
+[server]
+port = 8080
+`)
+	defer cleanup()
+
+	module := files.NewDocumentModule(target, "server.port", 9090)
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	content, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "title = \"welfare\"")
+	assert.Contains(t, string(content), "port = 9090")
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+
+	module = files.NewDocumentModule(target, "title", nil)
+	module.Absent = true
+	changed, err := module.Run()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	content, err = ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.NotContains(t, string(content), "title")
+}
+
+func TestDocumentModule_ExecuteWithNullInTOML(t *testing.T) {
+	content := "[[servers]]\nport = 8080\n"
+	target, cleanup := createFile(t, "config.toml", content)
+	defer cleanup()
+
+	_, err := files.NewDocumentModule(target, "servers.0.port", nil).Execute(context.Background(), false)
+	assert.EqualError(t, err, "value of servers.0.port in "+target+" must not be null, TOML has no null values")
+
+	_, err = files.NewDocumentModule(target, "servers.0", nil).Execute(context.Background(), false)
+	assert.EqualError(t, err, "value of servers.0 in "+target+" must not be null, TOML has no null values")
+	assertContent(t, content, target)
+}
+
+func TestDocumentModule_ExecuteWithCreateAndFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "document")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "config")
+	module := files.NewDocumentModule(target, "server.port", 8080)
+	module.Create = true
+
+	_, err = module.Execute(context.Background(), false)
+	assert.EqualError(t, err, "could not detect format of "+target+", please declare the format")
+
+	module.Format = files.JSONFormat
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "created file "+target, result.Message)
+	assertContent(t, "{\n  \"server\": {\n    \"port\": 8080\n  }\n}\n", target)
+}
+
+func TestDocumentModule_ExecuteWithInvalidPath(t *testing.T) {
+	target, cleanup := createFile(t, "config.yml", "server: localhost\nports: [80]\n")
+	defer cleanup()
+
+	_, err := files.NewDocumentModule(target, "server.port", 8080).Execute(context.Background(), false)
+	assert.EqualError(t, err, "failed to edit server.port of "+target+": port is neither part of a map nor of a list")
+
+	_, err = files.NewDocumentModule(target, "ports.1", 443).Execute(context.Background(), false)
+	assert.EqualError(t, err, "failed to edit ports.1 of "+target+": 1 is not an index of a list with 1 items")
+
+	_, err = files.NewDocumentModule(target, "server..port", 8080).Execute(context.Background(), false)
+	assert.EqualError(t, err, "invalid key server..port, the names of the key must not be empty")
+}
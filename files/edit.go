@@ -0,0 +1,156 @@
+package files
+
+import (
+	"context"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// missingFile declares how editFile treats a file which does not exist
+type missingFile int
+
+const (
+	// failMissing reports a missing file as error
+	failMissing missingFile = iota
+	// createMissing creates the file with the edited empty content
+	createMissing
+	// ignoreMissing leaves the file missing without a change
+	ignoreMissing
+)
+
+// contentEdit edits the current content of a file. It returns the new content and a description of the change, such as
+// "changed line in". The description is empty if the content is unchanged.
+type contentEdit func(current string) (string, string, error)
+
+// editFile applies the edit to the content of the file at path and replaces the content, if the edit changed it. The
+// file keeps its mode and owner unless they are declared by perms, the owner names of perms must be resolved. The
+// new content is validated and backed up like the content of ensureContent.
+func editFile(ctx context.Context, result *welfare.Result, path string, perms permissions, options replaceOptions, missing missingFile, edit contentEdit, check bool) (bool, error) {
+	target, err := collectFileInfo(path)
+	if err != nil {
+		return false, err
+	}
+	result.Before = target.state()
+	result.After = result.Before
+
+	current := ""
+	switch target.State {
+	case File:
+		data, err := ioutil.ReadFile(target.Path)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to read %s", target.Path)
+		}
+		current = string(data)
+	case Absent:
+		if missing == ignoreMissing {
+			return false, nil
+		}
+		if missing == failMissing {
+			return false, errors.Errorf("%s does not exist", target.Path)
+		}
+	default:
+		return false, errors.Errorf("%s is not a regular file", target.Path)
+	}
+
+	content, message, err := edit(current)
+	if err != nil {
+		return false, err
+	}
+	// unchanged content is kept as it is, even if the edit would have normalized it
+	if message == "" {
+		content = current
+	}
+
+	perms, err = perms.withMode(currentMode(target, 0644), false)
+	if err != nil {
+		return false, err
+	}
+	expected := replacementPermissions(target, perms)
+
+	contentChanged, err := ensureContent(ctx, result, target, content, expected, options, check)
+	if err != nil {
+		return false, err
+	}
+
+	permissionsChanged, err := ensurePermissions(expected, target, check)
+	if err != nil {
+		return false, err
+	}
+
+	checksum, err := contentChecksum([]byte(content))
+	if err != nil {
+		return false, err
+	}
+
+	result.After = fileInfo{permissions: expected, State: File, Checksum: checksum}.state()
+	if target.State == Absent {
+		result.Message = "created file " + target.Path
+	} else if contentChanged {
+		result.Message = message + " " + target.Path
+	} else if permissionsChanged {
+		result.Message = "changed permissions of " + target.Path
+	}
+
+	return contentChanged || permissionsChanged, nil
+}
+
+// contentLines splits the content into lines without line endings
+func contentLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// joinLines joins the lines with line endings, every line is terminated by a newline
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// lastMatch returns the index of the last line which matches the expression, or -1 if no line matches
+func lastMatch(lines []string, expression *regexp.Regexp) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if expression.MatchString(lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertIndex returns the index at which new lines are inserted. The lines are inserted before the last line which
+// matches insertBefore or after the last line which matches insertAfter. If beginning is true they are inserted at the
+// beginning and without a matching line they are appended.
+func insertIndex(lines []string, insertAfter, insertBefore *regexp.Regexp, beginning bool) int {
+	switch {
+	case beginning:
+		return 0
+	case insertBefore != nil:
+		if match := lastMatch(lines, insertBefore); match >= 0 {
+			return match
+		}
+	case insertAfter != nil:
+		if match := lastMatch(lines, insertAfter); match >= 0 {
+			return match + 1
+		}
+	}
+	return len(lines)
+}
+
+// compileOptional compiles the expression, an empty expression or the keyword is returned as nil
+func compileOptional(expression string, keyword string) (*regexp.Regexp, error) {
+	if expression == "" || expression == keyword {
+		return nil, nil
+	}
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid regexp %s", expression)
+	}
+	return compiled, nil
+}
@@ -1,9 +1,13 @@
 package files
 
 import (
+	"context"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewFileModule creates new FileModule for the given path
@@ -24,68 +28,234 @@ func NewFileModule(path string, state State) *FileModule {
 	return module
 }
 
-// FileModule ensures the state of a path
+// FileModule ensures the state of a path. The permissions are not applied to links, because they would change the
+// file the link points to.
 type FileModule struct {
 	permissions
+	replaceOptions
 	Path    string
 	Content string
 	State   State
+	// Src is the file a link points to, it is required for the states Link and Hard
+	Src string
+	// Force replaces an existing file or link at the path with a link
+	Force bool
+	// ModTime is the modification time of the state Touch
+	ModTime Timestamp
+	// AccessTime is the access time of the state Touch
+	AccessTime Timestamp
+	// Recurse applies the owner and the mode of the state Directory to every directory within the tree and the owner
+	// and RecurseFileMode to every file
+	Recurse bool
+	// RecurseFileMode is the mode of the files within a recursive directory, zero keeps the mode of the files
+	RecurseFileMode os.FileMode
 }
 
 func (module *FileModule) Run() (bool, error) {
-	target, err := collectFileInfo(module.Path)
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the path, without touching it
+func (module *FileModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the state of the path, in check mode it only reports what would change
+func (module *FileModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	if err := ctx.Err(); err != nil {
+		return result.Finish(false, err)
+	}
+
+	perms, err := module.permissions.resolve()
 	if err != nil {
-		return false, err
+		return result.Finish(false, err)
 	}
 
+	var target fileInfo
+	switch module.State {
+	case Link, Hard, Absent:
+		target, err = collectLinkInfo(module.Path)
+	default:
+		target, err = collectFileInfo(module.Path)
+	}
+	if err != nil {
+		return result.Finish(false, err)
+	}
+
+	// a symbolic mode is applied to the current mode of the path, or to the default mode of a missing path
+	dir := module.State == Directory || target.State == Directory
+	perms, err = perms.withMode(currentMode(target, perms.FileMode), dir)
+	if err != nil {
+		return result.Finish(false, err)
+	}
+	result.Before = target.state()
+	result.After = result.Before
+
+	changed := false
 	switch module.State {
 	case File:
-		return module.file(target)
+		changed, err = module.file(ctx, result, target, perms, check)
 	case Directory:
-		return module.directory(target)
+		changed, err = module.directory(result, target, perms, check)
 	case Absent:
-		return module.absent(target)
+		changed, err = module.absent(result, target, check)
+	case Link:
+		changed, err = module.link(result, target, check)
+	case Hard:
+		changed, err = module.hard(result, target, check)
+	case Touch:
+		changed, err = module.touch(result, target, perms, check)
 	default:
-		return false, errors.New("not yet implemented")
+		err = errors.New("not yet implemented")
 	}
+	return result.Finish(changed, err)
 }
 
-func (module *FileModule) file(target fileInfo) (bool, error) {
-	contentChanged, err := ensureContent(target, module.Content, module.FileMode)
+func (module *FileModule) file(ctx context.Context, result *welfare.Result, target fileInfo, perms permissions, check bool) (bool, error) {
+	contentChanged, err := ensureContent(ctx, result, target, module.Content, perms, module.replaceOptions, check)
 	if err != nil {
 		return false, err
 	}
 
-	permissionsChanged, err := ensurePermissions(module.permissions, target)
+	permissionsChanged, err := ensurePermissions(perms, target, check)
 	if err != nil {
 		return false, err
 	}
 
+	checksum, err := contentChecksum([]byte(module.Content))
+	if err != nil {
+		return false, err
+	}
+
+	result.After = fileInfo{permissions: perms, State: File, Checksum: checksum}.state()
+	if target.State == Absent {
+		result.Message = "created file " + target.Path
+	} else if contentChanged {
+		result.Message = "changed content of " + target.Path
+	} else if permissionsChanged {
+		result.Message = "changed permissions of " + target.Path
+	}
+
 	return contentChanged || permissionsChanged, nil
 }
 
-func (module *FileModule) directory(target fileInfo) (bool, error) {
+func (module *FileModule) directory(result *welfare.Result, target fileInfo, perms permissions, check bool) (bool, error) {
 	directoryChanged := false
 
 	switch target.State {
 	case Absent:
-		err := os.MkdirAll(target.Path, module.FileMode)
+		directoryChanged = true
+		if !check {
+			err := os.MkdirAll(target.Path, perms.FileMode)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to create directory %s", target.Path)
+			}
+		}
+	}
+
+	permissionsChanged, err := ensurePermissions(perms, target, check)
+	if err != nil {
+		return false, err
+	}
+
+	if module.Recurse && target.State == Directory {
+		filePermissions := permissions{FileMode: module.RecurseFileMode, UID: perms.UID, GID: perms.GID}
+		result.ChangedPaths, err = ensureRecursivePermissions(target.Path, perms, filePermissions, check)
 		if err != nil {
-			return false, errors.Wrapf(err, "failed to create directory %s", target.Path)
+			return false, err
 		}
+	}
 
-		directoryChanged = true
+	result.After = fileInfo{permissions: perms, State: Directory}.state()
+	if directoryChanged {
+		result.Message = "created directory " + target.Path
+	} else if len(result.ChangedPaths) > 0 {
+		result.Message = "changed permissions of " + strconv.Itoa(len(result.ChangedPaths)) + " paths within " + target.Path
+	} else if permissionsChanged {
+		result.Message = "changed permissions of " + target.Path
 	}
 
-	permissionsChanged, err := ensurePermissions(module.permissions, target)
+	return directoryChanged || permissionsChanged || len(result.ChangedPaths) > 0, nil
+}
+
+func (module *FileModule) touch(result *welfare.Result, target fileInfo, perms permissions, check bool) (bool, error) {
+	if target.State != File && target.State != Directory && target.State != Absent {
+		return false, errors.Errorf("%s seams to be not a regular file", target.Path)
+	}
+
+	now := time.Now()
+	created := target.State == Absent
+	if created && !check {
+		file, err := os.OpenFile(target.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perms.FileMode)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to create file %s", target.Path)
+		}
+		file.Close()
+	}
+
+	modTime := module.ModTime.resolve(target.ModTime, now)
+	accessTime := module.AccessTime.resolve(target.AccessTime, now)
+	timesChanged := !modTime.Equal(target.ModTime) || !accessTime.Equal(target.AccessTime)
+	if timesChanged && !check {
+		err := os.Chtimes(target.Path, accessTime, modTime)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to change timestamps of %s", target.Path)
+		}
+	}
+
+	// the default mode of the module is meant for files, so a touched directory keeps its mode unless the mode is
+	// declared symbolic
+	if target.State == Directory && perms.SymbolicMode == "" {
+		perms.FileMode = target.FileMode
+	}
+
+	permissionsChanged, err := ensurePermissions(perms, target, check)
 	if err != nil {
 		return false, err
 	}
 
-	return directoryChanged || permissionsChanged, nil
+	after := fileInfo{permissions: perms, State: File, Checksum: target.Checksum}
+	if target.State == Directory {
+		after.State = Directory
+	} else if created {
+		after.Checksum, err = contentChecksum(nil)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	result.After = after.state()
+	if created {
+		result.Message = "created file " + target.Path
+	} else if timesChanged {
+		result.Message = "touched " + target.Path
+	} else if permissionsChanged {
+		result.Message = "changed permissions of " + target.Path
+	}
+
+	return created || timesChanged || permissionsChanged, nil
 }
 
-func (module *FileModule) absent(target fileInfo) (bool, error) {
+func (module *FileModule) absent(result *welfare.Result, target fileInfo, check bool) (bool, error) {
+	result.After = welfare.State{}
+	if target.State != Absent {
+		result.Message = "removed " + target.Path
+	}
+
+	if check {
+		return target.State != Absent, nil
+	}
+
+	if target.State != Absent && module.enabled() {
+		var err error
+		result.Backup, err = module.backupAndRemove(target)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	switch target.State {
 	case Absent:
 		return false, nil
@@ -95,6 +265,12 @@ func (module *FileModule) absent(target fileInfo) (bool, error) {
 			return false, errors.Wrapf(err, "failed to remove file %s", target.Path)
 		}
 		return true, nil
+	case Link:
+		err := os.Remove(target.Path)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to remove link %s", target.Path)
+		}
+		return true, nil
 	case Directory:
 		err := os.RemoveAll(target.Path)
 		if err != nil {
@@ -105,3 +281,128 @@ func (module *FileModule) absent(target fileInfo) (bool, error) {
 		return false, errors.New("not yet implemented")
 	}
 }
+
+func (module *FileModule) link(result *welfare.Result, target fileInfo, check bool) (bool, error) {
+	if module.Src == "" {
+		return false, errors.Errorf("link %s requires a source", target.Path)
+	}
+
+	switch target.State {
+	case Link:
+		if target.LinkTarget == module.Src {
+			return false, nil
+		}
+		result.Message = "changed link " + target.Path + " from " + target.LinkTarget + " to " + module.Src
+	case Absent:
+		result.Message = "created link " + target.Path + " to " + module.Src
+	default:
+		err := module.ensureReplaceable(target)
+		if err != nil {
+			return false, err
+		}
+		result.Message = "replaced " + target.Path + " with link to " + module.Src
+	}
+
+	result.After = welfare.State{Exists: true, Link: module.Src}
+	if check {
+		return true, nil
+	}
+
+	err := module.replaceWithLink(result, target, func(path string) error {
+		return os.Symlink(module.Src, path)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (module *FileModule) hard(result *welfare.Result, target fileInfo, check bool) (bool, error) {
+	if module.Src == "" {
+		return false, errors.Errorf("hard link %s requires a source", target.Path)
+	}
+
+	source, err := collectFileInfo(module.Src)
+	if err != nil {
+		return false, err
+	}
+	if source.State != File {
+		return false, errors.Errorf("source %s of hard link %s is not a file", module.Src, target.Path)
+	}
+
+	switch target.State {
+	case Absent:
+		result.Message = "created hard link " + target.Path + " to " + module.Src
+	case File:
+		same, err := sameFile(module.Src, target.Path)
+		if err != nil {
+			return false, err
+		}
+		if same {
+			return false, nil
+		}
+		fallthrough
+	default:
+		err := module.ensureReplaceable(target)
+		if err != nil {
+			return false, err
+		}
+		result.Message = "replaced " + target.Path + " with hard link to " + module.Src
+	}
+
+	result.After = source.state()
+	if check {
+		return true, nil
+	}
+
+	err = module.replaceWithLink(result, target, func(path string) error {
+		return os.Link(module.Src, path)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ensureReplaceable returns an error, if the existing target must not be replaced by a link
+func (module *FileModule) ensureReplaceable(target fileInfo) error {
+	if target.State == Directory {
+		return errors.Errorf("%s is a directory and cannot be replaced by a link", target.Path)
+	}
+	if !module.Force {
+		return errors.Errorf("%s already exists, use force to replace it with a link", target.Path)
+	}
+	return nil
+}
+
+// replaceWithLink backs up an existing file at the target and replaces the target atomically with a link
+func (module *FileModule) replaceWithLink(result *welfare.Result, target fileInfo, create func(path string) error) error {
+	if target.State == Absent {
+		err := create(target.Path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create link %s", target.Path)
+		}
+		return nil
+	}
+
+	var err error
+	result.Backup, err = module.backupFile(target)
+	if err != nil {
+		return err
+	}
+	return replaceWithLink(target.Path, create)
+}
+
+// sameFile returns true if both paths refer to the same file
+func sameFile(first, second string) (bool, error) {
+	firstStat, err := os.Stat(first)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to stat %s", first)
+	}
+
+	secondStat, err := os.Lstat(second)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to stat %s", second)
+	}
+	return os.SameFile(firstStat, secondStat), nil
+}
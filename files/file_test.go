@@ -1,11 +1,15 @@
 package files_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/sdorra/welfare"
 	"github.com/sdorra/welfare/files"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -198,3 +202,545 @@ func TestFileModule_RunWithStateDirectoryChangePermissions(t *testing.T) {
 
 	assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
 }
+
+func TestFileModule_CheckWithStateFileAndOtherContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hi My Name is."), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.File)
+	file.Content = "Hello My Name is"
+
+	changed, err := file.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	bytes, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi My Name is.", string(bytes))
+}
+
+func TestFileModule_CheckWithStateDirectoryNonExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+
+	file := files.NewFileModule(target, files.Directory)
+	changed, err := file.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileModule_CheckWithStateAbsentExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hello My Name is"), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Absent)
+	changed, err := file.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	_, err = os.Stat(target)
+	assert.Nil(t, err)
+}
+
+func TestFileModule_CheckWithStateFileAndContentExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hello My Name is"), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.File)
+	file.Content = "Hello My Name is"
+
+	changed, err := file.Check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+}
+
+func TestFileModule_ExecuteWithStateFileAndOtherContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hi My Name is."), 0600)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.File)
+	file.Content = "Hello My Name is"
+
+	result, err := file.Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, os.FileMode(0600), result.Before.Mode)
+	assert.Equal(t, os.FileMode(0644), result.After.Mode)
+	assert.NotEqual(t, result.Before.Checksum, result.After.Checksum)
+	assert.Contains(t, result.Diff, "-Hi My Name is.")
+	assert.Contains(t, result.Diff, "+Hello My Name is")
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assert.Equal(t, result.Before, result.After)
+	assert.Empty(t, result.Diff)
+}
+
+func TestFileModule_ExecuteWithBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hi My Name is."), 0600)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.File)
+	file.Content = "Hello My Name is"
+	file.Backup = true
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^`+target+`\.\d{4}-\d{2}-\d{2}@\d{2}:\d{2}~$`, result.Backup)
+
+	bytes, err := ioutil.ReadFile(result.Backup)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi My Name is.", string(bytes))
+
+	stat, err := os.Stat(result.Backup)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode())
+
+	file.Content = "Hello My Name is Slim Shady"
+	second, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.NotEqual(t, result.Backup, second.Backup)
+}
+
+func TestFileModule_ExecuteWithStateAbsentAndBackupDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = os.Mkdir(target, 0755)
+	require.Nil(t, err)
+	err = ioutil.WriteFile(path.Join(target, "config"), []byte("Hello"), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Absent)
+	file.BackupDir = path.Join(dir, "backups")
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, file.BackupDir, path.Dir(result.Backup))
+
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+
+	bytes, err := ioutil.ReadFile(path.Join(result.Backup, "config"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", string(bytes))
+}
+
+func TestFileModule_ExecuteWithStateAbsentAndBackupDirOnOtherFilesystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	backupDir, err := ioutil.TempDir("/dev/shm", "backups")
+	if err != nil {
+		t.Skip("no tmpfs at /dev/shm")
+	}
+	defer os.RemoveAll(backupDir)
+
+	target := path.Join(dir, "target")
+	err = os.Mkdir(target, 0750)
+	require.Nil(t, err)
+	err = ioutil.WriteFile(path.Join(target, "config"), []byte("Hello"), 0600)
+	require.Nil(t, err)
+	err = os.Symlink("config", path.Join(target, "current"))
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Absent)
+	file.BackupDir = backupDir
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+
+	stat, err := os.Stat(result.Backup)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750), stat.Mode().Perm())
+
+	bytes, err := ioutil.ReadFile(path.Join(result.Backup, "current"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", string(bytes))
+
+	stat, err = os.Stat(path.Join(result.Backup, "config"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
+
+func TestFileModule_ExecuteWithStateLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "default")
+	file := files.NewFileModule(target, files.Link)
+	file.Src = "../sites-available/default"
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.False(t, result.Before.Exists)
+	assert.Equal(t, "../sites-available/default", result.After.Link)
+
+	link, err := os.Readlink(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "../sites-available/default", link)
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assert.Equal(t, result.Before, result.After)
+}
+
+func TestFileModule_ExecuteWithStateLinkPointingElsewhere(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "java")
+	err = os.Symlink("/usr/lib/jvm/java-8/bin/java", target)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Link)
+	file.Src = "/usr/lib/jvm/java-11/bin/java"
+
+	result, err := file.Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "/usr/lib/jvm/java-8/bin/java", result.Before.Link)
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Contains(t, result.Message, "from /usr/lib/jvm/java-8/bin/java to /usr/lib/jvm/java-11/bin/java")
+
+	link, err := os.Readlink(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "/usr/lib/jvm/java-11/bin/java", link)
+}
+
+func TestFileModule_ExecuteWithStateLinkAndExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hello"), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Link)
+	file.Src = "/etc/hostname"
+
+	_, err = file.Execute(context.Background(), false)
+	assert.EqualError(t, err, target+" already exists, use force to replace it with a link")
+
+	file.Force = true
+	file.Backup = true
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.NotEmpty(t, result.Backup)
+
+	link, err := os.Readlink(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/hostname", link)
+}
+
+func TestFileModule_ExecuteWithStateHard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	err = ioutil.WriteFile(source, []byte("Hello"), 0644)
+	require.Nil(t, err)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hello"), 0644)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Hard)
+	file.Src = source
+	file.Force = true
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	sourceStat, err := os.Stat(source)
+	require.Nil(t, err)
+	targetStat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.True(t, os.SameFile(sourceStat, targetStat))
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestFileModule_ExecuteWithStateAbsentDanglingLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = os.Symlink(path.Join(dir, "missing"), target)
+	require.Nil(t, err)
+
+	file := files.NewFileModule(target, files.Absent)
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	_, err = os.Lstat(target)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileModule_ExecuteWithStateTouch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "marker")
+	file := files.NewFileModule(target, files.Touch)
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "created file "+target, result.Message)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), stat.Size())
+
+	// touching an existing file without explicit times always updates the timestamps
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "touched "+target, result.Message)
+}
+
+func TestFileModule_ExecuteWithStateTouchAndExplicitTimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "marker")
+	err = ioutil.WriteFile(target, []byte("Hello"), 0644)
+	require.Nil(t, err)
+
+	modTime := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	file := files.NewFileModule(target, files.Touch)
+	file.ModTime = files.Timestamp{Time: modTime}
+	file.AccessTime = files.Timestamp{Preserve: true}
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.True(t, modTime.Equal(stat.ModTime()))
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+
+	bytes, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", string(bytes))
+}
+
+func TestFileModule_ExecuteWithStateTouchKeepsModeOfDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "repositories")
+	require.Nil(t, os.Mkdir(target, 0750))
+	require.Nil(t, os.Chmod(target, 0750))
+
+	file := files.NewFileModule(target, files.Touch)
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "touched "+target, result.Message)
+	assert.Equal(t, os.FileMode(0750), result.After.Mode)
+	assert.Empty(t, result.After.Checksum)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.True(t, stat.IsDir())
+	assert.Equal(t, os.FileMode(0750), stat.Mode().Perm())
+}
+
+func TestFileModule_ExecuteWithStateDirectoryAndRecurse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "scm")
+	require.Nil(t, os.MkdirAll(path.Join(target, "repositories", "git"), 0700))
+	require.Nil(t, ioutil.WriteFile(path.Join(target, "config.xml"), []byte("<config/>"), 0600))
+	require.Nil(t, ioutil.WriteFile(path.Join(target, "repositories", "index"), []byte("git"), 0640))
+	require.Nil(t, os.Chmod(target, 0755))
+
+	file := files.NewFileModule(target, files.Directory)
+	file.Recurse = true
+	file.RecurseFileMode = 0640
+
+	result, err := file.Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Len(t, result.ChangedPaths, 3)
+
+	stat, err := os.Stat(path.Join(target, "config.xml"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, []string{
+		path.Join(target, "config.xml"),
+		path.Join(target, "repositories"),
+		path.Join(target, "repositories", "git"),
+	}, result.ChangedPaths)
+	assert.Equal(t, "changed permissions of 3 paths within "+target, result.Message)
+
+	stat, err = os.Stat(path.Join(target, "repositories", "git"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+
+	stat, err = os.Stat(path.Join(target, "config.xml"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), stat.Mode().Perm())
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assert.Empty(t, result.ChangedPaths)
+}
+
+func TestFileModule_ExecuteWithOwnerAndGroupNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	root := path.Join(dir, "root")
+	require.Nil(t, os.MkdirAll(path.Join(root, "etc"), 0755))
+	passwd := "root:x:0:0:root:/root:/bin/bash\nwelfare:x:" + strconv.Itoa(os.Getuid()) + ":100::/home/welfare:/bin/sh\n"
+	require.Nil(t, ioutil.WriteFile(path.Join(root, "etc", "passwd"), []byte(passwd), 0644))
+	group := "root:x:0:\nwelfare:x:" + strconv.Itoa(os.Getgid()) + ":\n"
+	require.Nil(t, ioutil.WriteFile(path.Join(root, "etc", "group"), []byte(group), 0644))
+
+	target := path.Join(dir, "target")
+	file := files.NewFileModule(target, files.File)
+	file.UID = 4711
+	file.GID = 4711
+	file.Owner = "welfare"
+	file.Group = "welfare"
+	file.SystemRoot = root
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, os.Getuid(), result.After.UID)
+	assert.Equal(t, os.Getgid(), result.After.GID)
+	assert.Equal(t, 4711, file.UID)
+
+	file.Owner = "www-data"
+	_, err = file.Execute(context.Background(), false)
+	assert.EqualError(t, err, "unknown user www-data, it is not listed in "+path.Join(root, "etc", "passwd"))
+}
+
+func TestFileModule_ExecuteWithSymbolicMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "deploy.sh")
+	require.Nil(t, ioutil.WriteFile(target, []byte("#!/bin/sh"), 0640))
+
+	file := files.NewFileModule(target, files.File)
+	file.Content = "#!/bin/sh"
+	file.SymbolicMode = "+x,o-r"
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, os.FileMode(0640), result.Before.Mode)
+	assert.Equal(t, os.FileMode(0751), result.After.Mode)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0751), stat.Mode().Perm())
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestFileModule_ExecuteWithSetgidDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "shared")
+	require.Nil(t, os.Mkdir(target, 0775))
+	require.Nil(t, os.Chmod(target, 0775))
+
+	file := files.NewFileModule(target, files.Directory)
+	file.FileMode = os.ModeSetgid | 0775
+
+	result, err := file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, os.ModeSetgid|0775, stat.Mode()&(os.ModePerm|os.ModeSetgid))
+
+	result, err = file.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
@@ -1,9 +1,11 @@
 package files
 
 import (
-	"io/ioutil"
+	"bytes"
+	"context"
 	"os"
 	"syscall"
+	"time"
 
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"crypto/sha256"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // State represents the state of a file
@@ -25,6 +28,12 @@ const (
 	Directory
 	// Absent represents the absent of the path
 	Absent
+	// Link represents a symbolic link
+	Link
+	// Hard represents a hard link
+	Hard
+	// Touch represents a file, which is created if it is missing and gets new timestamps otherwise
+	Touch
 )
 
 type fileInfo struct {
@@ -32,20 +41,25 @@ type fileInfo struct {
 	Path     string
 	State    State
 	Checksum string
+	Size     int64
+	// LinkTarget is the path a symbolic link points to
+	LinkTarget string
+	ModTime    time.Time
+	AccessTime time.Time
 }
 
 type permissions struct {
 	FileMode os.FileMode
-	UID      int
-	GID      int
-}
-
-func collectAndMergeFileInfo(path string, settings permissions) (fileInfo, error) {
-	info, err := collectFileInfo(path)
-	if err != nil {
-		return info, err
-	}
-	return mergeFilePermissions(info, settings), nil
+	// SymbolicMode is a chmod style mode such as u+x, which is applied to the current mode and overrides FileMode
+	SymbolicMode string
+	UID          int
+	GID          int
+	// Owner is the name of the owner, it overrides UID
+	Owner string
+	// Group is the name of the group, it overrides GID
+	Group string
+	// SystemRoot is the directory whose etc/passwd and etc/group are used to resolve Owner and Group, it defaults to /
+	SystemRoot string
 }
 
 func collectFileInfo(path string) (fileInfo, error) {
@@ -71,9 +85,10 @@ func collectFileInfo(path string) (fileInfo, error) {
 		}
 
 		file.Checksum = hash
+		file.Size = stat.Size()
 	}
 
-	file.FileMode = stat.Mode().Perm()
+	file.FileMode = fileMode(stat.Mode())
 	sysStat, cast := stat.Sys().(*syscall.Stat_t)
 	if !cast {
 		return file, errors.New("stat not of type syscall.Stat_t")
@@ -81,10 +96,64 @@ func collectFileInfo(path string) (fileInfo, error) {
 
 	file.UID = int(sysStat.Uid)
 	file.GID = int(sysStat.Gid)
+	file.ModTime = stat.ModTime()
+	file.AccessTime = time.Unix(sysStat.Atim.Unix())
 
 	return file, nil
 }
 
+// collectLinkInfo collects the info of the path like collectFileInfo, but a symbolic link at the path is not followed.
+// A symbolic link is reported with the state Link and the path it points to.
+func collectLinkInfo(path string) (fileInfo, error) {
+	file := fileInfo{Path: path}
+
+	stat, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			file.State = Absent
+			return file, nil
+		}
+		return file, errors.Wrapf(err, "failed to stat %s", path)
+	}
+
+	if stat.Mode()&os.ModeSymlink == 0 {
+		return collectFileInfo(path)
+	}
+
+	file.State = Link
+	file.LinkTarget, err = os.Readlink(path)
+	if err != nil {
+		return file, errors.Wrapf(err, "failed to read link %s", path)
+	}
+
+	file.FileMode = fileMode(stat.Mode())
+	sysStat, cast := stat.Sys().(*syscall.Stat_t)
+	if !cast {
+		return file, errors.New("stat not of type syscall.Stat_t")
+	}
+
+	file.UID = int(sysStat.Uid)
+	file.GID = int(sysStat.Gid)
+
+	return file, nil
+}
+
+// state converts the fileInfo to the welfare representation of a state
+func (info fileInfo) state() welfare.State {
+	if info.State == Absent {
+		return welfare.State{}
+	}
+
+	return welfare.State{
+		Exists:   true,
+		Mode:     info.FileMode,
+		UID:      info.UID,
+		GID:      info.GID,
+		Checksum: info.Checksum,
+		Link:     info.LinkTarget,
+	}
+}
+
 func mergeFilePermissions(info fileInfo, settings permissions) fileInfo {
 	if settings.FileMode > 0 {
 		info.FileMode = settings.FileMode
@@ -124,24 +193,48 @@ func hashToString(hashAlg hash.Hash) string {
 	return fmt.Sprintf("%x", hashAlg.Sum(nil))
 }
 
-func ensureContent(target fileInfo, content string, mode os.FileMode) (bool, error) {
-	bytes := []byte(content)
+func contentChecksum(content []byte) (string, error) {
+	hashAlg := createHashAlg()
+	_, err := hashAlg.Write(content)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create checksum for content")
+	}
+	return hashToString(hashAlg), nil
+}
+
+// ensureContent ensures the content of the target and stores a diff of the content change in the result. The content
+// is validated and written atomically with the expected permissions, after the replaced content was backed up.
+func ensureContent(ctx context.Context, result *welfare.Result, target fileInfo, content string, expected permissions, options replaceOptions, check bool) (bool, error) {
+	data := []byte(content)
 	if target.State == File {
-		hashAlg := createHashAlg()
-		_, err := hashAlg.Write(bytes)
+		hash, err := contentChecksum(data)
 		if err != nil {
-			return false, errors.Wrap(err, "failed to create checksum for content")
+			return false, err
 		}
-		hash := hashToString(hashAlg)
 		if hash != target.Checksum {
-			err := ioutil.WriteFile(target.Path, bytes, mode)
+			result.Diff, err = contentDiff(target, data)
+			if err != nil {
+				return false, err
+			}
+			if check {
+				return true, nil
+			}
+			err = options.replace(ctx, result, target, bytes.NewReader(data), expected)
 			if err != nil {
 				return false, errors.Wrapf(err, "failed to overwrite content of %s", target.Path)
 			}
 			return true, nil
 		}
 	} else if target.State == Absent {
-		err := ioutil.WriteFile(target.Path, bytes, mode)
+		var err error
+		result.Diff, err = contentDiff(target, data)
+		if err != nil {
+			return false, err
+		}
+		if check {
+			return true, nil
+		}
+		err = options.replace(ctx, result, target, bytes.NewReader(data), expected)
 		if err != nil {
 			return false, errors.Wrapf(err, "failed to write content to %s", target.Path)
 		}
@@ -152,23 +245,29 @@ func ensureContent(target fileInfo, content string, mode os.FileMode) (bool, err
 	return false, nil
 }
 
-func ensurePermissions(expected permissions, target fileInfo) (bool, error) {
-	modeChanged := false
-	if expected.FileMode != target.FileMode {
-		err := os.Chmod(target.Path, expected.FileMode)
-		if err != nil {
-			return false, errors.Wrapf(err, "failed to change mode of %s", target.Path)
-		}
-		modeChanged = true
-	}
-
+// ensurePermissions changes owner and mode of the target to the expected permissions. The owner is changed first,
+// because changing the owner clears the setuid and setgid bits.
+func ensurePermissions(expected permissions, target fileInfo, check bool) (bool, error) {
 	ownershipChanged := false
 	if expected.UID != target.UID || expected.GID != target.GID {
-		err := os.Chown(target.Path, expected.UID, expected.GID)
-		if err != nil {
-			return false, errors.Wrapf(err, "failed to change mode of %s", target.Path)
+		if !check {
+			err := os.Chown(target.Path, expected.UID, expected.GID)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to change mode of %s", target.Path)
+			}
 		}
 		ownershipChanged = true
 	}
+
+	modeChanged := false
+	if expected.FileMode != target.FileMode {
+		if !check {
+			err := os.Chmod(target.Path, expected.FileMode)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to change mode of %s", target.Path)
+			}
+		}
+		modeChanged = true
+	}
 	return modeChanged || ownershipChanged, nil
 }
@@ -0,0 +1,30 @@
+package files_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createFile writes the content to a file with the name in a new temporary directory, the returned function removes
+// the directory
+func createFile(t *testing.T, name string, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "files")
+	require.Nil(t, err)
+
+	target := path.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(target, []byte(content), 0644))
+	return target, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func assertContent(t *testing.T, expected string, path string) {
+	content, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(content))
+}
@@ -0,0 +1,249 @@
+package files
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// NewIniFileModule creates a new IniFileModule, which sets the option of the section in the file at path to the value
+func NewIniFileModule(path string, section string, option string, value string) *IniFileModule {
+	module := &IniFileModule{
+		Path:    path,
+		Section: section,
+		Option:  option,
+		Value:   value,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// IniFileModule ensures a single option of an ini file, comments, the order of the options and every other line of the
+// file are left untouched. Mode and owner of an existing file are kept, unless they are declared by the module.
+type IniFileModule struct {
+	permissions
+	replaceOptions
+	Path string
+	// Section is the name of the section without brackets, the empty section contains the options before the first
+	// section of the file
+	Section string
+	// Option is the name of the option, without an option only the section is ensured
+	Option string
+	Value  string
+	// Absent removes the option or, without Option, the whole section
+	Absent bool
+	// NoExtraSpaces writes new options as option=value instead of option = value
+	NoExtraSpaces bool
+	// Create creates the file if it is missing, otherwise a missing file is an error
+	Create bool
+}
+
+func (module *IniFileModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the file, without touching it
+func (module *IniFileModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the option in the file, in check mode it only reports what would change
+func (module *IniFileModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *IniFileModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if module.Option == "" && module.Section == "" {
+		return false, errors.Errorf("ini file %s requires a section or an option", module.Path)
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	missing := failMissing
+	if module.Absent {
+		missing = ignoreMissing
+	} else if module.Create {
+		missing = createMissing
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, missing, func(current string) (string, string, error) {
+		lines, message := module.edit(contentLines(current))
+		return joinLines(lines), message, nil
+	}, check)
+}
+
+// edit applies the option or section to the lines of the file, it returns the new lines and a description of the
+// change. The description is empty if the lines are unchanged.
+func (module *IniFileModule) edit(lines []string) ([]string, string) {
+	start, end := findSection(lines, module.Section)
+
+	if module.Option == "" {
+		switch {
+		case module.Absent && start >= 0:
+			// the options before the first section have no header, which could be removed
+			if module.Section == "" {
+				return lines, ""
+			}
+			return append(lines[:start], lines[end:]...), "removed section " + module.Section + " from"
+		case !module.Absent && start < 0:
+			return module.appendSection(lines, nil), "added section " + module.Section + " to"
+		default:
+			return lines, ""
+		}
+	}
+
+	if start < 0 {
+		if module.Absent {
+			return lines, ""
+		}
+		return module.appendSection(lines, []string{module.optionLine("")}), "set " + module.Option + " in"
+	}
+
+	options := []int{}
+	for i := start; i < end; i++ {
+		if name, _, ok := parseOption(lines[i]); ok && name == module.Option {
+			options = append(options, i)
+		}
+	}
+
+	if module.Absent {
+		if len(options) == 0 {
+			return lines, ""
+		}
+		for i := len(options) - 1; i >= 0; i-- {
+			lines = append(lines[:options[i]], lines[options[i]+1:]...)
+		}
+		return lines, "removed " + module.Option + " from"
+	}
+
+	if len(options) == 0 {
+		index := lastContentLine(lines, start, end) + 1
+		line := module.optionLine(sectionIndentation(lines, start, end))
+		return append(lines[:index], append([]string{line}, lines[index:]...)...), "set " + module.Option + " in"
+	}
+
+	changed := false
+	for _, index := range options {
+		if _, value, _ := parseOption(lines[index]); value != module.Value {
+			lines[index] = module.optionLine(indentation(lines[index]))
+			changed = true
+		}
+	}
+	if !changed {
+		return lines, ""
+	}
+	return lines, "set " + module.Option + " in"
+}
+
+// appendSection appends the section with the option lines to the end of the file, separated by an empty line
+func (module *IniFileModule) appendSection(lines []string, options []string) []string {
+	if module.Section != "" {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+module.Section+"]")
+	}
+	return append(lines, options...)
+}
+
+func (module *IniFileModule) optionLine(indent string) string {
+	if module.NoExtraSpaces {
+		return indent + module.Option + "=" + module.Value
+	}
+	return indent + module.Option + " = " + module.Value
+}
+
+// findSection returns the index of the first line and the index after the last line of the section, the first line is
+// the header of the section. The empty section starts at the beginning of the file and ends before the first section.
+// Both indexes are -1, if the file does not contain the section.
+func findSection(lines []string, section string) (int, int) {
+	start := -1
+	if section == "" {
+		start = 0
+	}
+	for i, line := range lines {
+		name, ok := parseSection(line)
+		if !ok {
+			continue
+		}
+		if start >= 0 {
+			return start, i
+		}
+		if name == section {
+			start = i
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	return start, len(lines)
+}
+
+func parseSection(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(line[1 : len(line)-1]), true
+}
+
+// parseOption returns name and value of an option line, comments, empty lines and section headers are no options
+func parseOption(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+		return "", "", false
+	}
+	if _, ok := parseSection(trimmed); ok {
+		return "", "", false
+	}
+
+	separator := strings.IndexAny(trimmed, "=:")
+	if separator < 0 {
+		return trimmed, "", true
+	}
+	return strings.TrimSpace(trimmed[:separator]), strings.TrimSpace(trimmed[separator+1:]), true
+}
+
+// lastContentLine returns the index of the last line of the section, which is neither empty nor a comment. Comments
+// and empty lines at the end of a section usually belong to the next section.
+func lastContentLine(lines []string, start, end int) int {
+	for i := end - 1; i >= start; i-- {
+		if _, _, ok := parseOption(lines[i]); ok {
+			return i
+		}
+	}
+	if start < end {
+		if _, ok := parseSection(lines[start]); ok {
+			return start
+		}
+	}
+	return start - 1
+}
+
+// sectionIndentation returns the indentation of the first option of the section, e.g. a tab in a git config
+func sectionIndentation(lines []string, start, end int) string {
+	for i := start; i < end; i++ {
+		if _, _, ok := parseOption(lines[i]); ok {
+			return indentation(lines[i])
+		}
+	}
+	return ""
+}
+
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,149 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const myCnf = `# managed by hand
+[client]
+port = 3306
+
+[mysqld]
+; the data directory
+datadir = /var/lib/mysql
+max_connections = 100
+
+# replication
+[mysqldump]
+quick
+`
+
+func TestIniFileModule_ExecuteUpdatesOption(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", myCnf)
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "mysqld", "max_connections", "500")
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "set max_connections in "+target, result.Message)
+	assert.Contains(t, result.Diff, "-max_connections = 100\n+max_connections = 500\n")
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestIniFileModule_ExecuteAddsOptionAfterLastOptionOfSection(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", myCnf)
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "mysqld", "bind-address", "127.0.0.1")
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	module = files.NewIniFileModule(target, "", "!includedir", "/etc/mysql/conf.d/")
+	module.NoExtraSpaces = true
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `!includedir=/etc/mysql/conf.d/
+# managed by hand
+[client]
+port = 3306
+
+[mysqld]
+; the data directory
+datadir = /var/lib/mysql
+max_connections = 100
+bind-address = 127.0.0.1
+
+# replication
+[mysqldump]
+quick
+`, target)
+}
+
+func TestIniFileModule_ExecuteAddsSection(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", myCnf)
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "mysql", "auto-rehash", "false")
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assertContent(t, myCnf+"\n[mysql]\nauto-rehash = false\n", target)
+}
+
+func TestIniFileModule_ExecuteKeepsIndentation(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", "[user]\n\tname = sorbot\n[core]\n\teditor = vi\n")
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "user", "email", "sorbot@example.com")
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	module = files.NewIniFileModule(target, "core", "editor", "vim")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, "[user]\n\tname = sorbot\n\temail = sorbot@example.com\n[core]\n\teditor = vim\n", target)
+}
+
+func TestIniFileModule_ExecuteRemovesOption(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", myCnf)
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "mysqldump", "quick", "")
+	module.Absent = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "removed quick from "+target, result.Message)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestIniFileModule_ExecuteRemovesSection(t *testing.T) {
+	target, cleanup := createFile(t, "my.cnf", myCnf)
+	defer cleanup()
+
+	module := files.NewIniFileModule(target, "mysqld", "", "")
+	module.Absent = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "removed section mysqld from "+target, result.Message)
+	assertContent(t, "# managed by hand\n[client]\nport = 3306\n\n[mysqldump]\nquick\n", target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestIniFileModule_ExecuteWithCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inifile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "pip.conf")
+	module := files.NewIniFileModule(target, "global", "index-url", "https://pypi.example.com/simple")
+	module.Create = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "created file "+target, result.Message)
+	assertContent(t, "[global]\nindex-url = https://pypi.example.com/simple\n", target)
+}
@@ -0,0 +1,191 @@
+package files
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+const (
+	// BeginningOfFile is the InsertBefore anchor, which inserts the line at the beginning of the file
+	BeginningOfFile = "BOF"
+	// EndOfFile is the InsertAfter anchor, which appends the line to the end of the file
+	EndOfFile = "EOF"
+)
+
+// NewLineInFileModule creates a new LineInFileModule, which ensures that the file at path contains the line
+func NewLineInFileModule(path string, line string) *LineInFileModule {
+	module := &LineInFileModule{
+		Path: path,
+		Line: line,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// LineInFileModule ensures that a single line of a file is present or absent, the rest of the file is left untouched.
+// Mode and owner of an existing file are kept, unless they are declared by the module.
+type LineInFileModule struct {
+	permissions
+	replaceOptions
+	Path string
+	// Line is the content of the line. With Backrefs it may refer to the groups of Regexp, e.g. ${1}.
+	Line string
+	// Regexp selects the line which is replaced by Line, if more than one line matches the last one is replaced. If
+	// Absent is true, every matching line is removed.
+	Regexp string
+	// Absent removes every line which matches Regexp or, without Regexp, every line which is equal to Line
+	Absent bool
+	// InsertAfter is a regular expression, a new line is inserted after the last matching line. EndOfFile or no match
+	// appends the line to the end of the file.
+	InsertAfter string
+	// InsertBefore is a regular expression, a new line is inserted before the last matching line. BeginningOfFile
+	// inserts the line at the beginning of the file.
+	InsertBefore string
+	// Backrefs expands the groups of Regexp in Line. The file is left unchanged, if no line matches Regexp.
+	Backrefs bool
+	// Create creates the file if it is missing, otherwise a missing file is an error
+	Create bool
+}
+
+func (module *LineInFileModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the file, without touching it
+func (module *LineInFileModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the line in the file, in check mode it only reports what would change
+func (module *LineInFileModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *LineInFileModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	expressions, err := module.compile()
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	missing := failMissing
+	if module.Absent {
+		missing = ignoreMissing
+	} else if module.Create {
+		missing = createMissing
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, missing, func(current string) (string, string, error) {
+		var lines []string
+		var message string
+		if module.Absent {
+			lines, message = module.remove(contentLines(current), expressions)
+		} else {
+			lines, message = module.ensure(contentLines(current), expressions)
+		}
+		return joinLines(lines), message, nil
+	}, check)
+}
+
+// lineExpressions are the compiled regular expressions of the module, an expression is nil if it is not declared
+type lineExpressions struct {
+	regexp       *regexp.Regexp
+	insertAfter  *regexp.Regexp
+	insertBefore *regexp.Regexp
+}
+
+func (module *LineInFileModule) compile() (lineExpressions, error) {
+	expressions := lineExpressions{}
+	if module.InsertAfter != "" && module.InsertBefore != "" {
+		return expressions, errors.New("insert after and insert before are mutually exclusive")
+	}
+	if module.Backrefs && module.Regexp == "" {
+		return expressions, errors.New("backrefs requires a regexp")
+	}
+
+	var err error
+	expressions.regexp, err = compileOptional(module.Regexp, "")
+	if err != nil {
+		return expressions, err
+	}
+	expressions.insertAfter, err = compileOptional(module.InsertAfter, EndOfFile)
+	if err != nil {
+		return expressions, err
+	}
+	expressions.insertBefore, err = compileOptional(module.InsertBefore, BeginningOfFile)
+	return expressions, err
+}
+
+// ensure replaces the last line which matches the regexp or inserts the line, it returns the new lines and a
+// description of the change. The description is empty if the lines are unchanged.
+func (module *LineInFileModule) ensure(lines []string, expressions lineExpressions) ([]string, string) {
+	if expressions.regexp != nil {
+		index := lastMatch(lines, expressions.regexp)
+		if index >= 0 {
+			line := module.Line
+			if module.Backrefs {
+				match := expressions.regexp.FindStringSubmatchIndex(lines[index])
+				line = string(expressions.regexp.ExpandString(nil, module.Line, lines[index], match))
+			}
+			if lines[index] == line {
+				return lines, ""
+			}
+			lines[index] = line
+			return lines, "changed line in"
+		}
+		if module.Backrefs {
+			return lines, ""
+		}
+	}
+
+	for _, line := range lines {
+		if line == module.Line {
+			return lines, ""
+		}
+	}
+
+	index := insertIndex(lines, expressions.insertAfter, expressions.insertBefore, module.InsertBefore == BeginningOfFile)
+	lines = append(lines[:index], append([]string{module.Line}, lines[index:]...)...)
+	return lines, "inserted line into"
+}
+
+// remove removes every line which matches the regexp or is equal to the line, it returns the remaining lines and a
+// description of the change. The description is empty if no line was removed.
+func (module *LineInFileModule) remove(lines []string, expressions lineExpressions) ([]string, string) {
+	remaining := []string{}
+	for _, line := range lines {
+		if expressions.regexp != nil && expressions.regexp.MatchString(line) {
+			continue
+		}
+		if expressions.regexp == nil && line == module.Line {
+			continue
+		}
+		remaining = append(remaining, line)
+	}
+
+	removed := len(lines) - len(remaining)
+	if removed == 0 {
+		return lines, ""
+	}
+	if removed == 1 {
+		return remaining, "removed 1 line from"
+	}
+	return remaining, "removed " + strconv.Itoa(removed) + " lines from"
+}
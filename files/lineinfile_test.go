@@ -0,0 +1,176 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sshdConfig = `Port 22
+#PermitRootLogin prohibit-password
+PasswordAuthentication yes
+UsePAM yes
+`
+
+func TestLineInFileModule_ExecuteReplacesMatchingLine(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig)
+	defer cleanup()
+	require.Nil(t, os.Chmod(target, 0600))
+
+	module := files.NewLineInFileModule(target, "PermitRootLogin no")
+	module.Regexp = "^#?PermitRootLogin"
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "changed line in "+target, result.Message)
+	assert.Contains(t, result.Diff, "-#PermitRootLogin prohibit-password\n+PermitRootLogin no\n")
+	assertContent(t, "Port 22\nPermitRootLogin no\nPasswordAuthentication yes\nUsePAM yes\n", target)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestLineInFileModule_CheckDoesNotChangeFile(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig)
+	defer cleanup()
+
+	module := files.NewLineInFileModule(target, "PermitRootLogin no")
+	module.Regexp = "^#?PermitRootLogin"
+
+	changed, err := module.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assertContent(t, sshdConfig, target)
+}
+
+func TestLineInFileModule_ExecuteInsertsLine(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig)
+	defer cleanup()
+
+	module := files.NewLineInFileModule(target, "AllowUsers deploy")
+	module.Regexp = "^AllowUsers"
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "inserted line into "+target, result.Message)
+	assertContent(t, sshdConfig+"AllowUsers deploy\n", target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestLineInFileModule_ExecuteWithInsertAfterAndInsertBefore(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig)
+	defer cleanup()
+
+	module := files.NewLineInFileModule(target, "ListenAddress 0.0.0.0")
+	module.InsertAfter = "^Port"
+	_, err := module.Run()
+	require.Nil(t, err)
+	assertContent(t, "Port 22\nListenAddress 0.0.0.0\n#PermitRootLogin prohibit-password\nPasswordAuthentication yes\nUsePAM yes\n", target)
+
+	module = files.NewLineInFileModule(target, "# managed by welfare")
+	module.InsertBefore = files.BeginningOfFile
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewLineInFileModule(target, "Protocol 2")
+	module.InsertBefore = "^UsePAM"
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, "# managed by welfare\nPort 22\nListenAddress 0.0.0.0\n#PermitRootLogin prohibit-password\n"+
+		"PasswordAuthentication yes\nProtocol 2\nUsePAM yes\n", target)
+}
+
+func TestLineInFileModule_ExecuteWithBackrefs(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig)
+	defer cleanup()
+
+	module := files.NewLineInFileModule(target, "${1} no")
+	module.Regexp = "^(PasswordAuthentication) yes$"
+	module.Backrefs = true
+
+	changed, err := module.Run()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assertContent(t, "Port 22\n#PermitRootLogin prohibit-password\nPasswordAuthentication no\nUsePAM yes\n", target)
+
+	module.Regexp = "^(ChallengeResponseAuthentication) yes$"
+	changed, err = module.Run()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+}
+
+func TestLineInFileModule_ExecuteWithAbsent(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", sshdConfig+"UsePAM yes\n")
+	defer cleanup()
+
+	module := files.NewLineInFileModule(target, "")
+	module.Regexp = "^UsePAM"
+	module.Absent = true
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "removed 2 lines from "+target, result.Message)
+	assertContent(t, "Port 22\n#PermitRootLogin prohibit-password\nPasswordAuthentication yes\n", target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+
+	module.Regexp = "^Port"
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "removed 1 line from "+target, result.Message)
+}
+
+func TestLineInFileModule_ExecuteWithMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lineinfile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "welfare.conf")
+	module := files.NewLineInFileModule(target, "enabled = true")
+
+	_, err = module.Execute(context.Background(), false)
+	assert.EqualError(t, err, target+" does not exist")
+
+	module.Create = true
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "created file "+target, result.Message)
+	assertContent(t, "enabled = true\n", target)
+
+	stat, err := os.Stat(target)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), stat.Mode().Perm())
+}
+
+func TestLineInFileModule_ExecuteKeepsContentWithoutFinalNewline(t *testing.T) {
+	target, cleanup := createFile(t, "sshd_config", "Port 22")
+	defer cleanup()
+	require.Nil(t, os.Chmod(target, 0600))
+
+	module := files.NewLineInFileModule(target, "Port 22")
+	module.FileMode = 0644
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "changed permissions of "+target, result.Message)
+	assertContent(t, "Port 22", target)
+}
@@ -0,0 +1,197 @@
+package files
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// specialBits are the setuid, setgid and sticky bits of a file mode
+const specialBits = os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// fileMode returns the permission bits of the mode including setuid, setgid and sticky
+func fileMode(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | specialBits)
+}
+
+// unixFileMode converts the octal representation of a unix mode, e.g. 02775, to a file mode
+func unixFileMode(bits uint64) os.FileMode {
+	mode := os.FileMode(bits) & os.ModePerm
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// withMode returns a copy of the permissions, whose FileMode is the SymbolicMode applied to the current mode. If the
+// permissions have no SymbolicMode, they are returned unchanged.
+func (perms permissions) withMode(current os.FileMode, dir bool) (permissions, error) {
+	if perms.SymbolicMode == "" {
+		return perms, nil
+	}
+
+	mode, err := applySymbolicMode(perms.SymbolicMode, current, dir)
+	if err != nil {
+		return perms, err
+	}
+	perms.FileMode = mode
+	return perms, nil
+}
+
+// currentMode returns the mode of the target, or the fallback if the target does not exist
+func currentMode(target fileInfo, fallback os.FileMode) os.FileMode {
+	if target.State == Absent {
+		return fallback
+	}
+	return target.FileMode
+}
+
+// applySymbolicMode applies a chmod style symbolic mode such as u=rwx,g=rx,o= or +x to the current mode. Every clause
+// consists of the classes u, g, o or a, followed by one or more operations of +, - or = with the permissions r, w, x,
+// X, s and t. A clause without classes applies to all classes. X sets the execute permission only for directories and
+// for files which are executable by any class.
+func applySymbolicMode(expression string, current os.FileMode, dir bool) (os.FileMode, error) {
+	mode := fileMode(current)
+	for _, clause := range strings.Split(expression, ",") {
+		i := 0
+		classes := ""
+		for i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0 {
+			classes += string(clause[i])
+			i++
+		}
+		if classes == "" || strings.Contains(classes, "a") {
+			classes = "ugo"
+		}
+
+		if i == len(clause) {
+			return 0, errors.Errorf("invalid symbolic mode %s, clause %s has no operation", expression, clause)
+		}
+
+		for i < len(clause) {
+			operator := clause[i]
+			if operator != '+' && operator != '-' && operator != '=' {
+				return 0, errors.Errorf("invalid symbolic mode %s, unexpected %c", expression, operator)
+			}
+			i++
+
+			executable := dir || mode&0111 != 0
+			var bits os.FileMode
+			for i < len(clause) && strings.IndexByte("+-=", clause[i]) < 0 {
+				permission, err := permissionBits(clause[i], classes, executable)
+				if err != nil {
+					return 0, errors.Wrapf(err, "invalid symbolic mode %s", expression)
+				}
+				bits |= permission
+				i++
+			}
+
+			switch operator {
+			case '+':
+				mode |= bits
+			case '-':
+				mode &^= bits
+			case '=':
+				mode = mode&^classBits(classes) | bits
+			}
+		}
+	}
+	return mode, nil
+}
+
+// permissionBits returns the bits of the permission for the classes
+func permissionBits(permission byte, classes string, executable bool) (os.FileMode, error) {
+	var bits os.FileMode
+	for _, class := range classes {
+		shift := classShift(class)
+		switch permission {
+		case 'r':
+			bits |= 04 << shift
+		case 'w':
+			bits |= 02 << shift
+		case 'x':
+			bits |= 01 << shift
+		case 'X':
+			if executable {
+				bits |= 01 << shift
+			}
+		case 's':
+			if class == 'u' {
+				bits |= os.ModeSetuid
+			} else if class == 'g' {
+				bits |= os.ModeSetgid
+			}
+		case 't':
+			if class == 'o' {
+				bits |= os.ModeSticky
+			}
+		default:
+			return 0, errors.Errorf("unknown permission %c", permission)
+		}
+	}
+	return bits, nil
+}
+
+// classBits returns all bits, which are cleared by = for the classes
+func classBits(classes string) os.FileMode {
+	var bits os.FileMode
+	for _, class := range classes {
+		bits |= 07 << classShift(class)
+		switch class {
+		case 'u':
+			bits |= os.ModeSetuid
+		case 'g':
+			bits |= os.ModeSetgid
+		case 'o':
+			bits |= os.ModeSticky
+		}
+	}
+	return bits
+}
+
+func classShift(class rune) uint {
+	switch class {
+	case 'u':
+		return 6
+	case 'g':
+		return 3
+	default:
+		return 0
+	}
+}
+
+// modeArgument is the mode argument of a playbook, which is either an octal mode or a symbolic mode
+type modeArgument struct {
+	mode     os.FileMode
+	symbolic string
+}
+
+// DecodeArgument decodes an octal string such as "2775", an integer or a symbolic mode such as u=rwx,g=rx,o=
+func (argument *modeArgument) DecodeArgument(raw interface{}) error {
+	switch value := raw.(type) {
+	case int:
+		argument.mode = unixFileMode(uint64(value))
+	case string:
+		bits, err := strconv.ParseUint(value, 8, 32)
+		if err == nil {
+			argument.mode = unixFileMode(bits)
+			return nil
+		}
+
+		_, err = applySymbolicMode(value, 0, false)
+		if err != nil {
+			return err
+		}
+		argument.symbolic = value
+	default:
+		return errors.Errorf("expected octal or symbolic file mode, got %T", raw)
+	}
+	return nil
+}
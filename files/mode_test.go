@@ -0,0 +1,48 @@
+package files
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySymbolicMode(t *testing.T) {
+	tests := []struct {
+		expression string
+		current    os.FileMode
+		dir        bool
+		expected   os.FileMode
+	}{
+		{"+x", 0644, false, 0755},
+		{"g-w", 0775, false, 0755},
+		{"u=rwx,g=rx,o=", 0644, false, 0750},
+		{"a=r", 0777, false, 0444},
+		{"go-rwx", 0755, false, 0700},
+		{"u+x,g+w-r", 0644, false, 0724},
+		{"a+X", 0644, false, 0644},
+		{"a+X", 0744, false, 0755},
+		{"a+X", 0600, true, 0711},
+		{"g+s", 0775, true, os.ModeSetgid | 0775},
+		{"u+s", 0755, false, os.ModeSetuid | 0755},
+		{"+t", 0777, true, os.ModeSticky | 0777},
+		{"g=rx", os.ModeSetgid | 0775, true, 0755},
+	}
+
+	for _, test := range tests {
+		mode, err := applySymbolicMode(test.expression, test.current, test.dir)
+		assert.Nil(t, err, test.expression)
+		assert.Equal(t, test.expected, mode, test.expression)
+	}
+}
+
+func TestApplySymbolicModeWithInvalidExpression(t *testing.T) {
+	_, err := applySymbolicMode("u", 0644, false)
+	assert.EqualError(t, err, "invalid symbolic mode u, clause u has no operation")
+
+	_, err = applySymbolicMode("u+q", 0644, false)
+	assert.EqualError(t, err, "invalid symbolic mode u+q: unknown permission q")
+
+	_, err = applySymbolicMode("rwx", 0644, false)
+	assert.EqualError(t, err, "invalid symbolic mode rwx, unexpected r")
+}
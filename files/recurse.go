@@ -0,0 +1,83 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ensureRecursivePermissions applies the permissions to every path within the directory, the directory itself is
+// left untouched. Directories get the mode of dirPermissions, files the mode of filePermissions and a zero mode keeps
+// the mode of the path. A symbolic mode is applied to the current mode of every path. Symbolic links are not followed
+// and only their owner is changed. Only paths which differ from the expected permissions are changed, the changed
+// paths are returned.
+func ensureRecursivePermissions(dir string, dirPermissions, filePermissions permissions, check bool) ([]string, error) {
+	changedPaths := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk %s", path)
+		}
+		if path == dir {
+			return nil
+		}
+
+		current, err := statPermissions(path, info)
+		if err != nil {
+			return err
+		}
+
+		expected := filePermissions
+		if info.IsDir() {
+			expected = dirPermissions
+		}
+		expected, err = expected.withMode(current.FileMode, info.IsDir())
+		if err != nil {
+			return err
+		}
+		expected = mergeFilePermissions(fileInfo{permissions: current}, expected).permissions
+
+		link := info.Mode()&os.ModeSymlink != 0
+		changed := false
+		if expected.UID != current.UID || expected.GID != current.GID {
+			changed = true
+			if !check {
+				err = os.Lchown(path, expected.UID, expected.GID)
+				if err != nil {
+					return errors.Wrapf(err, "failed to change owner of %s", path)
+				}
+			}
+		}
+
+		if !link && expected.FileMode != current.FileMode {
+			changed = true
+			if !check {
+				err = os.Chmod(path, expected.FileMode)
+				if err != nil {
+					return errors.Wrapf(err, "failed to change mode of %s", path)
+				}
+			}
+		}
+
+		if changed {
+			changedPaths = append(changedPaths, path)
+		}
+		return nil
+	})
+	return changedPaths, err
+}
+
+// statPermissions extracts the permissions from the result of a stat call for the path
+func statPermissions(path string, info os.FileInfo) (permissions, error) {
+	sysStat, cast := info.Sys().(*syscall.Stat_t)
+	if !cast {
+		return permissions{}, errors.Errorf("stat of %s not of type syscall.Stat_t", path)
+	}
+
+	return permissions{
+		FileMode: fileMode(info.Mode()),
+		UID:      int(sysStat.Uid),
+		GID:      int(sysStat.Gid),
+	}, nil
+}
@@ -0,0 +1,383 @@
+package files
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+func init() {
+	welfare.Register("file", newFileModuleFromArguments)
+	welfare.Register("copy", newCopyModuleFromArguments)
+	welfare.Register("template", newTemplateModuleFromArguments)
+	welfare.Register("lineinfile", newLineInFileModuleFromArguments)
+	welfare.Register("blockinfile", newBlockInFileModuleFromArguments)
+	welfare.Register("replace", newReplaceModuleFromArguments)
+	welfare.Register("ini_file", newIniFileModuleFromArguments)
+	welfare.Register("document", newDocumentModuleFromArguments)
+	welfare.Register("xml", newXMLModuleFromArguments)
+}
+
+// attributeArguments are the arguments for the mode, the owner, the backup and the validation of a file, which are
+// shared by every module of the package
+type attributeArguments struct {
+	Mode       modeArgument `welfare:"mode"`
+	UID        int          `welfare:"uid"`
+	GID        int          `welfare:"gid"`
+	Owner      string       `welfare:"owner"`
+	Group      string       `welfare:"group"`
+	SystemRoot string       `welfare:"system_root"`
+	Backup     bool         `welfare:"backup"`
+	BackupDir  string       `welfare:"backup_dir"`
+	Validate   string       `welfare:"validate"`
+}
+
+// newAttributeArguments returns the defaults of the arguments, negative ids keep the owner of the module
+func newAttributeArguments() attributeArguments {
+	return attributeArguments{UID: -1, GID: -1}
+}
+
+type fileArguments struct {
+	attributeArguments
+	Path       string       `welfare:"path,required"`
+	State      string       `welfare:"state"`
+	Content    string       `welfare:"content"`
+	Src        string       `welfare:"src"`
+	Force      bool         `welfare:"force"`
+	ModTime    string       `welfare:"mtime"`
+	AccessTime string       `welfare:"atime"`
+	Recurse    bool         `welfare:"recurse"`
+	FileMode   modeArgument `welfare:"file_mode"`
+}
+
+type copyArguments struct {
+	attributeArguments
+	Source          string   `welfare:"source,required"`
+	Target          string   `welfare:"target,required"`
+	PreserveModTime bool     `welfare:"preserve_mtime"`
+	Delete          bool     `welfare:"delete"`
+	Include         []string `welfare:"include"`
+	Exclude         []string `welfare:"exclude"`
+}
+
+type templateArguments struct {
+	attributeArguments
+	Target   string      `welfare:"target,required"`
+	Template string      `welfare:"template,required"`
+	Context  interface{} `welfare:"context"`
+}
+
+type lineInFileArguments struct {
+	attributeArguments
+	Path         string `welfare:"path,required"`
+	Line         string `welfare:"line"`
+	Regexp       string `welfare:"regexp"`
+	State        string `welfare:"state"`
+	InsertAfter  string `welfare:"insert_after"`
+	InsertBefore string `welfare:"insert_before"`
+	Backrefs     bool   `welfare:"backrefs"`
+	Create       bool   `welfare:"create"`
+}
+
+type blockInFileArguments struct {
+	attributeArguments
+	Path         string `welfare:"path,required"`
+	Name         string `welfare:"name,required"`
+	Block        string `welfare:"block"`
+	Marker       string `welfare:"marker"`
+	State        string `welfare:"state"`
+	InsertAfter  string `welfare:"insert_after"`
+	InsertBefore string `welfare:"insert_before"`
+	Create       bool   `welfare:"create"`
+}
+
+type replaceArguments struct {
+	attributeArguments
+	Path        string `welfare:"path,required"`
+	Regexp      string `welfare:"regexp,required"`
+	Replacement string `welfare:"replace"`
+	After       string `welfare:"after"`
+	Before      string `welfare:"before"`
+	Multiline   bool   `welfare:"multiline"`
+}
+
+type iniFileArguments struct {
+	attributeArguments
+	Path          string `welfare:"path,required"`
+	Section       string `welfare:"section"`
+	Option        string `welfare:"option"`
+	Value         string `welfare:"value"`
+	State         string `welfare:"state"`
+	NoExtraSpaces bool   `welfare:"no_extra_spaces"`
+	Create        bool   `welfare:"create"`
+}
+
+type documentArguments struct {
+	attributeArguments
+	Path   string      `welfare:"path,required"`
+	Key    string      `welfare:"key,required"`
+	Value  interface{} `welfare:"value"`
+	Format string      `welfare:"format"`
+	State  string      `welfare:"state"`
+	Create bool        `welfare:"create"`
+}
+
+type xmlArguments struct {
+	attributeArguments
+	Path   string `welfare:"path,required"`
+	XPath  string `welfare:"xpath,required"`
+	Value  string `welfare:"value"`
+	State  string `welfare:"state"`
+	Create bool   `welfare:"create"`
+}
+
+func newFileModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := fileArguments{State: "file", attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := parseState(arguments.State)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "state", Reason: err.Error()}
+	}
+
+	module := NewFileModule(arguments.Path, state)
+	module.Content = arguments.Content
+	module.Src = arguments.Src
+	module.Force = arguments.Force
+	module.Recurse = arguments.Recurse
+	if arguments.FileMode.symbolic != "" {
+		return nil, &welfare.ArgumentError{Field: "file_mode", Reason: "expected octal file mode"}
+	}
+	module.RecurseFileMode = arguments.FileMode.mode
+
+	module.ModTime, err = parseTimestamp(arguments.ModTime)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "mtime", Reason: err.Error()}
+	}
+
+	module.AccessTime, err = parseTimestamp(arguments.AccessTime)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "atime", Reason: err.Error()}
+	}
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newCopyModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := copyArguments{attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewCopyModule(arguments.Source, arguments.Target)
+	module.PreserveModTime = arguments.PreserveModTime
+	module.Delete = arguments.Delete
+	module.Include = arguments.Include
+	module.Exclude = arguments.Exclude
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newTemplateModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := templateArguments{attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewTemplateModule(arguments.Target, arguments.Template, arguments.Context)
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newLineInFileModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := lineInFileArguments{State: "present", attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewLineInFileModule(arguments.Path, arguments.Line)
+	module.Regexp = arguments.Regexp
+	module.InsertAfter = arguments.InsertAfter
+	module.InsertBefore = arguments.InsertBefore
+	module.Backrefs = arguments.Backrefs
+	module.Create = arguments.Create
+
+	switch arguments.State {
+	case "present":
+		if _, ok := args["line"]; !ok {
+			return nil, &welfare.ArgumentError{Field: "line", Reason: "is required for state present"}
+		}
+	case "absent":
+		if arguments.Line == "" && arguments.Regexp == "" {
+			return nil, &welfare.ArgumentError{Field: "regexp", Reason: "or line is required for state absent"}
+		}
+		module.Absent = true
+	default:
+		return nil, &welfare.ArgumentError{Field: "state", Reason: "unknown state " + arguments.State}
+	}
+
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newBlockInFileModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := blockInFileArguments{State: "present", Marker: DefaultMarker, attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewBlockInFileModule(arguments.Path, arguments.Name, arguments.Block)
+	module.Marker = arguments.Marker
+	module.InsertAfter = arguments.InsertAfter
+	module.InsertBefore = arguments.InsertBefore
+	module.Create = arguments.Create
+
+	switch arguments.State {
+	case "present":
+	case "absent":
+		module.Absent = true
+	default:
+		return nil, &welfare.ArgumentError{Field: "state", Reason: "unknown state " + arguments.State}
+	}
+
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newReplaceModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := replaceArguments{attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewReplaceModule(arguments.Path, arguments.Regexp, arguments.Replacement)
+	module.After = arguments.After
+	module.Before = arguments.Before
+	module.Multiline = arguments.Multiline
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newIniFileModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := iniFileArguments{State: "present", attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if arguments.Section == "" && arguments.Option == "" {
+		return nil, &welfare.ArgumentError{Field: "section", Reason: "or option is required"}
+	}
+
+	module := NewIniFileModule(arguments.Path, arguments.Section, arguments.Option, arguments.Value)
+	module.NoExtraSpaces = arguments.NoExtraSpaces
+	module.Create = arguments.Create
+
+	switch arguments.State {
+	case "present":
+	case "absent":
+		module.Absent = true
+	default:
+		return nil, &welfare.ArgumentError{Field: "state", Reason: "unknown state " + arguments.State}
+	}
+
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newDocumentModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := documentArguments{State: "present", attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewDocumentModule(arguments.Path, arguments.Key, arguments.Value)
+	module.Format = arguments.Format
+	module.Create = arguments.Create
+
+	switch arguments.State {
+	case "present":
+		if _, ok := args["value"]; !ok {
+			return nil, &welfare.ArgumentError{Field: "value", Reason: "is required for state present"}
+		}
+	case "absent":
+		module.Absent = true
+	default:
+		return nil, &welfare.ArgumentError{Field: "state", Reason: "unknown state " + arguments.State}
+	}
+
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+func newXMLModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := xmlArguments{State: "present", attributeArguments: newAttributeArguments()}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	module := NewXMLModule(arguments.Path, arguments.XPath, arguments.Value)
+	module.Create = arguments.Create
+
+	switch arguments.State {
+	case "present":
+	case "absent":
+		module.Absent = true
+	default:
+		return nil, &welfare.ArgumentError{Field: "state", Reason: "unknown state " + arguments.State}
+	}
+
+	arguments.apply(&module.permissions, &module.replaceOptions)
+	return module, nil
+}
+
+// apply overrides the defaults of the module with the permissions and replace options, which are declared in the
+// arguments
+func (arguments attributeArguments) apply(perms *permissions, options *replaceOptions) {
+	if arguments.Mode.mode > 0 {
+		perms.FileMode = arguments.Mode.mode
+	}
+	perms.SymbolicMode = arguments.Mode.symbolic
+	if arguments.UID >= 0 {
+		perms.UID = arguments.UID
+	}
+	if arguments.GID >= 0 {
+		perms.GID = arguments.GID
+	}
+	perms.Owner = arguments.Owner
+	perms.Group = arguments.Group
+	perms.SystemRoot = arguments.SystemRoot
+
+	*options = replaceOptions{
+		backupOptions{Backup: arguments.Backup, BackupDir: arguments.BackupDir},
+		validation{Validate: arguments.Validate},
+	}
+}
+
+func parseState(value string) (State, error) {
+	switch value {
+	case "file":
+		return File, nil
+	case "directory":
+		return Directory, nil
+	case "absent":
+		return Absent, nil
+	case "link":
+		return Link, nil
+	case "hard":
+		return Hard, nil
+	case "touch":
+		return Touch, nil
+	default:
+		return File, errors.Errorf("unknown state %s", value)
+	}
+}
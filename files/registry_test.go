@@ -0,0 +1,271 @@
+package files_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_File(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- file: {path: /etc/welfare, state: directory, mode: "0700"}
+`))
+	require.Nil(t, err)
+
+	file, ok := playbook.Tasks[0].Module.(*files.FileModule)
+	require.True(t, ok)
+	assert.Equal(t, "/etc/welfare", file.Path)
+	assert.Equal(t, files.State(files.Directory), file.State)
+	assert.Equal(t, os.FileMode(0700), file.FileMode)
+	assert.Equal(t, os.Getuid(), file.UID)
+}
+
+func TestRegistry_FileWithRecurse(t *testing.T) {
+	module, err := welfare.NewModule("file", welfare.Arguments{
+		"path":      "/var/lib/scm",
+		"state":     "directory",
+		"recurse":   true,
+		"mode":      "0750",
+		"file_mode": "0640",
+	})
+	require.Nil(t, err)
+
+	file := module.(*files.FileModule)
+	assert.True(t, file.Recurse)
+	assert.Equal(t, os.FileMode(0750), file.FileMode)
+	assert.Equal(t, os.FileMode(0640), file.RecurseFileMode)
+}
+
+func TestRegistry_FileWithSymbolicMode(t *testing.T) {
+	module, err := welfare.NewModule("file", welfare.Arguments{"path": "/usr/local/bin/welfare", "mode": "u+x,g-w"})
+	require.Nil(t, err)
+
+	file := module.(*files.FileModule)
+	assert.Equal(t, "u+x,g-w", file.SymbolicMode)
+	assert.Equal(t, os.FileMode(0644), file.FileMode)
+
+	module, err = welfare.NewModule("file", welfare.Arguments{"path": "/srv/shared", "state": "directory", "mode": "2775"})
+	require.Nil(t, err)
+	assert.Equal(t, os.ModeSetgid|0775, module.(*files.FileModule).FileMode)
+
+	_, err = welfare.NewModule("file", welfare.Arguments{"path": "/usr/local/bin/welfare", "mode": "u+y"})
+	assert.EqualError(t, err, "module file: invalid argument mode: invalid symbolic mode u+y: unknown permission y")
+
+	_, err = welfare.NewModule("file", welfare.Arguments{"path": "/srv", "state": "directory", "recurse": true, "file_mode": "g+w"})
+	assert.EqualError(t, err, "module file: invalid argument file_mode: expected octal file mode")
+}
+
+func TestRegistry_FileWithInvalidState(t *testing.T) {
+	_, err := welfare.NewModule("file", welfare.Arguments{"path": "/etc/welfare", "state": "dir"})
+	assert.EqualError(t, err, "module file: invalid argument state: unknown state dir")
+}
+
+func TestRegistry_FileWithLink(t *testing.T) {
+	module, err := welfare.NewModule("file", welfare.Arguments{
+		"path":  "/etc/nginx/sites-enabled/default",
+		"state": "link",
+		"src":   "/etc/nginx/sites-available/default",
+		"force": true,
+	})
+	require.Nil(t, err)
+
+	file := module.(*files.FileModule)
+	assert.Equal(t, files.State(files.Link), file.State)
+	assert.Equal(t, "/etc/nginx/sites-available/default", file.Src)
+	assert.True(t, file.Force)
+}
+
+func TestRegistry_FileWithTouch(t *testing.T) {
+	module, err := welfare.NewModule("file", welfare.Arguments{
+		"path":  "/var/lib/welfare/marker",
+		"state": "touch",
+		"mtime": "2026-10-18T12:00:00Z",
+		"atime": "preserve",
+	})
+	require.Nil(t, err)
+
+	file := module.(*files.FileModule)
+	assert.Equal(t, files.State(files.Touch), file.State)
+	assert.Equal(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), file.ModTime.Time)
+	assert.True(t, file.AccessTime.Preserve)
+
+	_, err = welfare.NewModule("file", welfare.Arguments{"path": "/var/lib/welfare/marker", "mtime": "yesterday"})
+	assert.EqualError(t, err, "module file: invalid argument mtime: expected now, preserve or RFC 3339 time, got yesterday")
+}
+
+func TestRegistry_Copy(t *testing.T) {
+	module, err := welfare.NewModule("copy", welfare.Arguments{"source": "a", "target": "b", "uid": 0})
+	require.Nil(t, err)
+
+	copy := module.(*files.CopyModule)
+	assert.Equal(t, "a", copy.Source)
+	assert.Equal(t, "b", copy.Target)
+	assert.Equal(t, 0, copy.UID)
+	assert.Equal(t, -1, copy.GID)
+}
+
+func TestRegistry_CopyWithOwnerNames(t *testing.T) {
+	module, err := welfare.NewModule("copy", welfare.Arguments{
+		"source":      "a",
+		"target":      "b",
+		"owner":       "www-data",
+		"group":       "adm",
+		"system_root": "/srv/chroot",
+	})
+	require.Nil(t, err)
+
+	copy := module.(*files.CopyModule)
+	assert.Equal(t, "www-data", copy.Owner)
+	assert.Equal(t, "adm", copy.Group)
+	assert.Equal(t, "/srv/chroot", copy.SystemRoot)
+}
+
+func TestRegistry_CopyDirectory(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- copy: {source: site, target: /var/www/site, delete: true, include: ["*.html", "*.css"], exclude: [.git]}
+`))
+	require.Nil(t, err)
+
+	copy := playbook.Tasks[0].Module.(*files.CopyModule)
+	assert.True(t, copy.Delete)
+	assert.Equal(t, []string{"*.html", "*.css"}, copy.Include)
+	assert.Equal(t, []string{".git"}, copy.Exclude)
+}
+
+func TestRegistry_Template(t *testing.T) {
+	module, err := welfare.NewModule("template", welfare.Arguments{
+		"target":     "/etc/welfare/config",
+		"template":   "name: {{.name}}",
+		"context":    map[string]interface{}{"name": "sorbot"},
+		"backup_dir": "/var/backups/welfare",
+	})
+	require.Nil(t, err)
+
+	template := module.(*files.TemplateModule)
+	assert.Equal(t, "/etc/welfare/config", template.Target)
+	assert.Equal(t, map[string]interface{}{"name": "sorbot"}, template.Context)
+	assert.Equal(t, "/var/backups/welfare", template.BackupDir)
+}
+
+func TestRegistry_LineInFile(t *testing.T) {
+	module, err := welfare.NewModule("lineinfile", welfare.Arguments{
+		"path":   "/etc/ssh/sshd_config",
+		"regexp": "^#?PermitRootLogin",
+		"line":   "PermitRootLogin no",
+		"backup": true,
+	})
+	require.Nil(t, err)
+
+	lineInFile := module.(*files.LineInFileModule)
+	assert.Equal(t, "/etc/ssh/sshd_config", lineInFile.Path)
+	assert.Equal(t, "PermitRootLogin no", lineInFile.Line)
+	assert.Equal(t, "^#?PermitRootLogin", lineInFile.Regexp)
+	assert.True(t, lineInFile.Backup)
+	assert.False(t, lineInFile.Absent)
+	assert.Equal(t, -1, lineInFile.UID)
+
+	module, err = welfare.NewModule("lineinfile", welfare.Arguments{"path": "/etc/hosts", "regexp": "old", "state": "absent"})
+	require.Nil(t, err)
+	assert.True(t, module.(*files.LineInFileModule).Absent)
+
+	_, err = welfare.NewModule("lineinfile", welfare.Arguments{"path": "/etc/hosts"})
+	assert.EqualError(t, err, "module lineinfile: invalid argument line: is required for state present")
+}
+
+func TestRegistry_BlockInFile(t *testing.T) {
+	module, err := welfare.NewModule("blockinfile", welfare.Arguments{
+		"path":          "/etc/hosts",
+		"name":          "scm",
+		"block":         "10.0.0.2 scm\n",
+		"insert_before": "BOF",
+	})
+	require.Nil(t, err)
+
+	blockInFile := module.(*files.BlockInFileModule)
+	assert.Equal(t, "scm", blockInFile.Name)
+	assert.Equal(t, "10.0.0.2 scm\n", blockInFile.Block)
+	assert.Equal(t, files.DefaultMarker, blockInFile.Marker)
+	assert.Equal(t, files.BeginningOfFile, blockInFile.InsertBefore)
+
+	_, err = welfare.NewModule("blockinfile", welfare.Arguments{"path": "/etc/hosts", "block": "10.0.0.2 scm"})
+	assert.EqualError(t, err, "module blockinfile: invalid argument name: is required")
+}
+
+func TestRegistry_Replace(t *testing.T) {
+	module, err := welfare.NewModule("replace", welfare.Arguments{
+		"path":      "/etc/apt/sources.list",
+		"regexp":    "http://",
+		"replace":   "https://",
+		"after":     "^# mirrors",
+		"multiline": true,
+	})
+	require.Nil(t, err)
+
+	replace := module.(*files.ReplaceModule)
+	assert.Equal(t, "http://", replace.Regexp)
+	assert.Equal(t, "https://", replace.Replacement)
+	assert.Equal(t, "^# mirrors", replace.After)
+	assert.True(t, replace.Multiline)
+
+	_, err = welfare.NewModule("replace", welfare.Arguments{"path": "/etc/apt/sources.list", "replace": "https://"})
+	assert.EqualError(t, err, "module replace: invalid argument regexp: is required")
+}
+
+func TestRegistry_IniFile(t *testing.T) {
+	module, err := welfare.NewModule("ini_file", welfare.Arguments{
+		"path":    "/etc/php/7.0/fpm/php.ini",
+		"section": "PHP",
+		"option":  "memory_limit",
+		"value":   "256M",
+	})
+	require.Nil(t, err)
+
+	iniFile := module.(*files.IniFileModule)
+	assert.Equal(t, "PHP", iniFile.Section)
+	assert.Equal(t, "memory_limit", iniFile.Option)
+	assert.Equal(t, "256M", iniFile.Value)
+	assert.False(t, iniFile.Absent)
+
+	_, err = welfare.NewModule("ini_file", welfare.Arguments{"path": "/etc/php/7.0/fpm/php.ini", "state": "absent"})
+	assert.EqualError(t, err, "module ini_file: invalid argument section: or option is required")
+}
+
+func TestRegistry_Document(t *testing.T) {
+	module, err := welfare.NewModule("document", welfare.Arguments{
+		"path":  "/etc/docker/daemon.json",
+		"key":   "log-opts",
+		"value": map[string]interface{}{"max-size": "10m"},
+	})
+	require.Nil(t, err)
+
+	document := module.(*files.DocumentModule)
+	assert.Equal(t, "log-opts", document.Key)
+	assert.Equal(t, map[string]interface{}{"max-size": "10m"}, document.Value)
+	assert.False(t, document.Absent)
+
+	_, err = welfare.NewModule("document", welfare.Arguments{"path": "/etc/docker/daemon.json", "key": "dns"})
+	assert.EqualError(t, err, "module document: invalid argument value: is required for state present")
+}
+
+func TestRegistry_XML(t *testing.T) {
+	module, err := welfare.NewModule("xml", welfare.Arguments{
+		"path":  "/opt/scm-server/conf/server-config.xml",
+		"xpath": "/server-config/connector[@port='8080']/@protocol",
+		"value": "HTTP/1.1",
+	})
+	require.Nil(t, err)
+
+	xml := module.(*files.XMLModule)
+	assert.Equal(t, "/server-config/connector[@port='8080']/@protocol", xml.XPath)
+	assert.Equal(t, "HTTP/1.1", xml.Value)
+	assert.False(t, xml.Absent)
+
+	_, err = welfare.NewModule("xml", welfare.Arguments{"path": "/opt/scm-server/conf/server-config.xml"})
+	assert.EqualError(t, err, "module xml: invalid argument xpath: is required")
+}
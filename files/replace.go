@@ -0,0 +1,128 @@
+package files
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// NewReplaceModule creates a new ReplaceModule, which replaces every match of the regular expression in the file at
+// path with the replacement
+func NewReplaceModule(path string, expression string, replacement string) *ReplaceModule {
+	module := &ReplaceModule{
+		Path:        path,
+		Regexp:      expression,
+		Replacement: replacement,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// ReplaceModule replaces every match of a regular expression in a file. The file is only written, if the replacement
+// changes its content. Mode and owner of the file are kept, unless they are declared by the module.
+type ReplaceModule struct {
+	permissions
+	replaceOptions
+	Path   string
+	Regexp string
+	// Replacement replaces every match, it may refer to the groups of Regexp, e.g. ${1}
+	Replacement string
+	// After is a regular expression, only the content after its first match is replaced. Without a match the file is
+	// left unchanged.
+	After string
+	// Before is a regular expression, only the content before its first match is replaced. Without a match the file is
+	// left unchanged.
+	Before string
+	// Multiline lets ^ and $ of Regexp match at the beginning and end of every line instead of the whole file
+	Multiline bool
+}
+
+func (module *ReplaceModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the file, without touching it
+func (module *ReplaceModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute replaces the matches in the file, in check mode it only reports what would change
+func (module *ReplaceModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *ReplaceModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if module.Regexp == "" {
+		return false, errors.Errorf("replace in %s requires a regexp", module.Path)
+	}
+	flags := ""
+	if module.Multiline {
+		flags = "(?m)"
+	}
+	expression, err := regexp.Compile(flags + module.Regexp)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid regexp %s", module.Regexp)
+	}
+	after, err := compileOptional(module.After, "")
+	if err != nil {
+		return false, err
+	}
+	before, err := compileOptional(module.Before, "")
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, failMissing, func(current string) (string, string, error) {
+		start, end, found := boundaries(current, after, before)
+		if !found {
+			return current, "", nil
+		}
+		region := current[start:end]
+
+		matches := len(expression.FindAllStringIndex(region, -1))
+		replaced := expression.ReplaceAllString(region, module.Replacement)
+		if replaced == region {
+			return current, "", nil
+		}
+		return current[:start] + replaced + current[end:], "replaced " + strconv.Itoa(matches) + " matches in", nil
+	}, check)
+}
+
+// boundaries returns the start and the end of the content between the first match of after and the first match of
+// before, which follows after. Without after or before the start or the end of the content is used. If after or before
+// is declared but does not match, false is returned.
+func boundaries(content string, after, before *regexp.Regexp) (int, int, bool) {
+	start, end := 0, len(content)
+	if after != nil {
+		match := after.FindStringIndex(content)
+		if match == nil {
+			return start, end, false
+		}
+		start = match[1]
+	}
+	if before != nil {
+		match := before.FindStringIndex(content[start:])
+		if match == nil {
+			return start, end, false
+		}
+		end = start + match[0]
+	}
+	return start, end, true
+}
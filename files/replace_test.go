@@ -0,0 +1,131 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sourcesList = `deb http://deb.debian.org/debian stretch main
+deb http://security.debian.org/debian-security stretch/updates main
+# deb http://ftp.debian.org/debian stretch-backports main
+`
+
+func TestReplaceModule_ExecuteReplacesEveryMatch(t *testing.T) {
+	target, cleanup := createFile(t, "sources.list", sourcesList)
+	defer cleanup()
+
+	module := files.NewReplaceModule(target, `http://`, "https://")
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "replaced 3 matches in "+target, result.Message)
+	assert.Contains(t, result.Diff, "+deb https://deb.debian.org/debian stretch main\n")
+	assertContent(t, `deb https://deb.debian.org/debian stretch main
+deb https://security.debian.org/debian-security stretch/updates main
+# deb https://ftp.debian.org/debian stretch-backports main
+`, target)
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestReplaceModule_CheckDoesNotChangeFile(t *testing.T) {
+	target, cleanup := createFile(t, "sources.list", sourcesList)
+	defer cleanup()
+
+	changed, err := files.NewReplaceModule(target, `stretch`, "buster").Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assertContent(t, sourcesList, target)
+}
+
+func TestReplaceModule_ExecuteWithGroupsAndMultiline(t *testing.T) {
+	target, cleanup := createFile(t, "sources.list", sourcesList)
+	defer cleanup()
+
+	module := files.NewReplaceModule(target, `^# (deb .*)$`, "${1}")
+	module.Multiline = true
+
+	changed, err := module.Run()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assertContent(t, `deb http://deb.debian.org/debian stretch main
+deb http://security.debian.org/debian-security stretch/updates main
+deb http://ftp.debian.org/debian stretch-backports main
+`, target)
+
+	module.Multiline = false
+	module.Regexp = `^deb`
+	module.Replacement = "deb-src"
+	changed, err = module.Run()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	content, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "deb-src"))
+}
+
+func TestReplaceModule_ExecuteWithBoundaries(t *testing.T) {
+	target, cleanup := createFile(t, "sources.list", "[main]\nurl = http://a\n[mirror]\nurl = http://b\n[backup]\nurl = http://c\n")
+	defer cleanup()
+
+	module := files.NewReplaceModule(target, `http://`, "https://")
+	module.After = `\[mirror\]`
+	module.Before = `\[backup\]`
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "replaced 1 matches in "+target, result.Message)
+	assertContent(t, "[main]\nurl = http://a\n[mirror]\nurl = https://b\n[backup]\nurl = http://c\n", target)
+}
+
+func TestReplaceModule_ExecuteWithAfterWithoutMatch(t *testing.T) {
+	content := "[main]\nurl = http://a\n[backup]\nurl = http://c\n"
+	target, cleanup := createFile(t, "sources.list", content)
+	defer cleanup()
+
+	module := files.NewReplaceModule(target, `http://`, "https://")
+	module.After = `\[mirror\]`
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assertContent(t, content, target)
+}
+
+func TestReplaceModule_ExecuteWithBeforeWithoutMatch(t *testing.T) {
+	content := "[main]\nurl = http://a\n[mirror]\nurl = http://b\n"
+	target, cleanup := createFile(t, "sources.list", content)
+	defer cleanup()
+
+	module := files.NewReplaceModule(target, `http://`, "https://")
+	module.After = `\[main\]`
+	module.Before = `\[backup\]`
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assertContent(t, content, target)
+}
+
+func TestReplaceModule_ExecuteWithMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "sources.list")
+	_, err = files.NewReplaceModule(target, `http://`, "https://").Execute(context.Background(), false)
+	assert.EqualError(t, err, target+" does not exist")
+}
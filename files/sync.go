@@ -0,0 +1,303 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// copyDirectory ensures that the target directory contains a copy of every selected file of the source directory.
+// Every file is compared by its checksum and only changed files are copied. With Delete, files of the target which are
+// not part of the source are removed, but files which are not selected by Include and Exclude are kept.
+func (module *CopyModule) copyDirectory(ctx context.Context, result *welfare.Result, source fileInfo, perms permissions, check bool) (bool, error) {
+	if module.enabled() {
+		return false, errors.Errorf("backup is not supported for directory %s", source.Path)
+	}
+	if module.Validate != "" {
+		return false, errors.Errorf("validate is not supported for directory %s", source.Path)
+	}
+
+	err := validatePatterns(module.Include, module.Exclude)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := collectFileInfo(module.Target)
+	if err != nil {
+		return false, err
+	}
+	if target.State != Directory && target.State != Absent {
+		return false, errors.Errorf("%s is not a directory", target.Path)
+	}
+	result.Before = target.state()
+
+	sourcePaths := map[string]bool{}
+	err = filepath.Walk(source.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk %s", path)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source.Path, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", path)
+		}
+
+		if !module.selected(relative, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		sourcePaths[relative] = true
+
+		targetPath := filepath.Join(module.Target, relative)
+		changed := false
+		switch {
+		case info.IsDir():
+			changed, err = module.ensureDirectory(path, targetPath, perms, check)
+		case info.Mode()&os.ModeSymlink != 0:
+			changed, err = ensureSymlink(path, targetPath, check)
+		default:
+			changed, err = module.ensureFile(ctx, result, path, targetPath, perms, check)
+		}
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			result.ChangedPaths = append(result.ChangedPaths, targetPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if module.Delete && target.State == Directory {
+		err = module.deleteExtraneous(result, sourcePaths, check)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	result.After = mergeFilePermissions(source, permissions{UID: perms.UID, GID: perms.GID}).state()
+	changed := len(result.ChangedPaths) > 0
+	if changed {
+		result.Message = "copied " + source.Path + " to " + target.Path + " (" +
+			strconv.Itoa(len(result.ChangedPaths)) + " changed paths)"
+	}
+	return changed, nil
+}
+
+// ensureDirectory ensures that the target directory exists with the permissions of the source directory. The owner
+// of the module overrides the owner of the source directory.
+func (module *CopyModule) ensureDirectory(sourcePath, targetPath string, perms permissions, check bool) (bool, error) {
+	source, err := collectFileInfo(sourcePath)
+	if err != nil {
+		return false, err
+	}
+	expected := mergeFilePermissions(source, permissions{UID: perms.UID, GID: perms.GID})
+
+	target, err := collectLinkInfo(targetPath)
+	if err != nil {
+		return false, err
+	}
+
+	created := false
+	switch target.State {
+	case Absent:
+		created = true
+		if !check {
+			err = os.Mkdir(targetPath, expected.FileMode)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to create directory %s", targetPath)
+			}
+		}
+	case Directory:
+	default:
+		return false, errors.Errorf("%s is not a directory", targetPath)
+	}
+
+	permissionsChanged, err := ensurePermissions(expected.permissions, target, check)
+	if err != nil {
+		return false, err
+	}
+	return created || permissionsChanged, nil
+}
+
+// ensureFile ensures that the target file is a copy of the source file and appends the diff to the result
+func (module *CopyModule) ensureFile(ctx context.Context, result *welfare.Result, sourcePath, targetPath string, perms permissions, check bool) (bool, error) {
+	source, err := collectFileInfo(sourcePath)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := collectLinkInfo(targetPath)
+	if err != nil {
+		return false, err
+	}
+	switch target.State {
+	case File, Absent:
+	case Link:
+		// writing through the link would change the file it points to, which may be outside of the target directory
+		if !check {
+			err = os.Remove(targetPath)
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to remove link %s", targetPath)
+			}
+		}
+		target = fileInfo{Path: targetPath, State: Absent}
+	default:
+		return false, errors.Errorf("%s is not a regular file", targetPath)
+	}
+
+	expected, err := expectedFileInfo(source, target, perms)
+	if err != nil {
+		return false, err
+	}
+
+	fileResult := &welfare.Result{}
+	changed, err := ensureCopy(ctx, fileResult, expected, target, module.replaceOptions, module.PreserveModTime, check)
+	result.Diff += fileResult.Diff
+	return changed, err
+}
+
+// ensureSymlink ensures that the target is a symbolic link, which points to the same path as the source link
+func ensureSymlink(sourcePath, targetPath string, check bool) (bool, error) {
+	link, err := os.Readlink(sourcePath)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read link %s", sourcePath)
+	}
+
+	target, err := collectLinkInfo(targetPath)
+	if err != nil {
+		return false, err
+	}
+
+	switch target.State {
+	case Link:
+		if target.LinkTarget == link {
+			return false, nil
+		}
+	case Absent, File:
+	default:
+		return false, errors.Errorf("%s is a directory and cannot be replaced by a link", targetPath)
+	}
+
+	if check {
+		return true, nil
+	}
+
+	create := func(path string) error {
+		return os.Symlink(link, path)
+	}
+	if target.State == Absent {
+		err = create(targetPath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to create link %s", targetPath)
+		}
+		return true, nil
+	}
+
+	err = replaceWithLink(targetPath, create)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteExtraneous removes every selected path of the target, which is not part of the source
+func (module *CopyModule) deleteExtraneous(result *welfare.Result, sourcePaths map[string]bool, check bool) error {
+	extraneous := []string{}
+	err := filepath.Walk(module.Target, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk %s", path)
+		}
+
+		relative, err := filepath.Rel(module.Target, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", path)
+		}
+
+		if !module.selected(relative, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !sourcePaths[relative] {
+			extraneous = append(extraneous, path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(extraneous)
+	for _, path := range extraneous {
+		if !check {
+			err := os.RemoveAll(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to remove %s", path)
+			}
+		}
+		result.ChangedPaths = append(result.ChangedPaths, path)
+	}
+	return nil
+}
+
+// selected returns true if the path relative to the source or target is selected by Include and Exclude. Excluded
+// directories are skipped with all of their content, Include applies only to files.
+func (module *CopyModule) selected(relative string, dir bool) bool {
+	if relative == "." {
+		return true
+	}
+	if matchesAny(module.Exclude, relative) {
+		return false
+	}
+	if dir || len(module.Include) == 0 {
+		return true
+	}
+	return matchesAny(module.Include, relative)
+}
+
+// matchesAny returns true if the relative path matches one of the glob patterns. Patterns which contain a slash are
+// matched against the whole relative path, others only against the name of the file.
+func matchesAny(patterns []string, relative string) bool {
+	for _, pattern := range patterns {
+		name := relative
+		if !strings.Contains(pattern, "/") {
+			name = filepath.Base(relative)
+		}
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePatterns(patternLists ...[]string) error {
+	for _, patterns := range patternLists {
+		for _, pattern := range patterns {
+			_, err := filepath.Match(pattern, "")
+			if err != nil {
+				return errors.Wrapf(err, "invalid pattern %s", pattern)
+			}
+		}
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package files
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"text/template"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewTemplateModule create a new TemplateModule for the target with the given template and context
@@ -25,12 +27,38 @@ func NewTemplateModule(target string, template string, context interface{}) *Tem
 // content of the evaluated template.
 type TemplateModule struct {
 	permissions
+	replaceOptions
 	Target   string
 	Template string
 	Context  interface{}
 }
 
 func (module *TemplateModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the target, without touching it
+func (module *TemplateModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the content and permissions of the target, in check mode it only reports what would change
+func (module *TemplateModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *TemplateModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
 	tpl, err := template.New(module.Target).Parse(module.Template)
 	if err != nil {
 		return false, errors.Wrap(err, "failed to parse template")
@@ -46,16 +74,35 @@ func (module *TemplateModule) Run() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	result.Before = target.state()
+
+	// a symbolic mode is applied to the current mode of the target, or to the default mode of a missing target
+	perms, err = perms.withMode(currentMode(target, perms.FileMode), false)
+	if err != nil {
+		return false, err
+	}
 
-	contentChanged, err := ensureContent(target, buffer.String(), module.FileMode)
+	contentChanged, err := ensureContent(ctx, result, target, buffer.String(), perms, module.replaceOptions, check)
 	if err != nil {
 		return false, err
 	}
 
-	permissionsChanged, err := ensurePermissions(module.permissions, target)
+	permissionsChanged, err := ensurePermissions(perms, target, check)
 	if err != nil {
 		return false, err
 	}
 
+	checksum, err := contentChecksum(buffer.Bytes())
+	if err != nil {
+		return false, err
+	}
+
+	result.After = fileInfo{permissions: perms, State: File, Checksum: checksum}.state()
+	if contentChanged {
+		result.Message = "rendered template to " + target.Path
+	} else if permissionsChanged {
+		result.Message = "changed permissions of " + target.Path
+	}
+
 	return contentChanged || permissionsChanged, nil
 }
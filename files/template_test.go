@@ -1,11 +1,13 @@
 package files_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
 
+	"github.com/sdorra/welfare"
 	"github.com/sdorra/welfare/files"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,6 +31,80 @@ func TestTemplateModule_Run(t *testing.T) {
 	assert.Equal(t, "Hello My Name is sorbot", string(bytes))
 }
 
+func TestTemplateModule_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+
+	tpl := files.NewTemplateModule(target, "Hello My Name is {{.Name}}", &Context{"sorbot"})
+
+	changed, err := tpl.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = tpl.Run()
+	require.Nil(t, err)
+
+	changed, err = tpl.Check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+}
+
+func TestTemplateModule_ExecuteWithValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+
+	tpl := files.NewTemplateModule(target, "Hello My Name is {{.Name}}", &Context{"sorbot"})
+	tpl.Validate = "grep -q sorbot %s"
+
+	result, err := tpl.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	require.Len(t, result.Commands, 1)
+	assert.Equal(t, dir, path.Dir(result.Commands[0].Args[3]))
+	assert.NotEqual(t, target, result.Commands[0].Args[3])
+
+	bytes, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello My Name is sorbot", string(bytes))
+}
+
+func TestTemplateModule_ExecuteWithFailedValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "target")
+	err = ioutil.WriteFile(target, []byte("Hello My Name is sorbot"), 0644)
+	require.Nil(t, err)
+
+	tpl := files.NewTemplateModule(target, "Hello My Name is {{.Name}}", &Context{"slim shady"})
+	tpl.Validate = "ls %s.invalid"
+
+	result, err := tpl.Execute(context.Background(), false)
+	assert.Error(t, err)
+	assert.Equal(t, welfare.Failed, result.Status)
+	assert.Contains(t, err.Error(), "validation with ls failed: exit status 2")
+	assert.Contains(t, err.Error(), "No such file or directory")
+	assert.Equal(t, 2, result.Commands[0].ExitCode)
+
+	bytes, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello My Name is sorbot", string(bytes))
+
+	infos, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, infos, 1)
+}
+
 type Context struct {
 	Name string
 }
@@ -0,0 +1,42 @@
+package files
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Timestamp declares the access or modification time of a file. The zero value is the current time.
+type Timestamp struct {
+	// Time is the declared time, the current time is used if it is zero
+	Time time.Time
+	// Preserve keeps the time of an existing file
+	Preserve bool
+}
+
+// resolve returns the time, which is declared by the timestamp
+func (timestamp Timestamp) resolve(current time.Time, now time.Time) time.Time {
+	if timestamp.Preserve && !current.IsZero() {
+		return current
+	}
+	if timestamp.Time.IsZero() {
+		return now
+	}
+	return timestamp.Time
+}
+
+// parseTimestamp parses now, preserve or a RFC 3339 time, an empty value is parsed as now
+func parseTimestamp(value string) (Timestamp, error) {
+	switch value {
+	case "", "now":
+		return Timestamp{}, nil
+	case "preserve":
+		return Timestamp{Preserve: true}, nil
+	default:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return Timestamp{}, errors.Errorf("expected now, preserve or RFC 3339 time, got %s", value)
+		}
+		return Timestamp{Time: parsed}, nil
+	}
+}
@@ -0,0 +1,71 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// resolve returns a copy of the permissions, whose UID and GID are resolved from Owner and Group. The names are looked
+// up in etc/passwd and etc/group below SystemRoot on every execution of a module, numeric names are used as ids. The
+// module itself keeps the names, so the resolved copy is passed on instead of the permissions of the module.
+func (perms permissions) resolve() (permissions, error) {
+	root := perms.SystemRoot
+	if root == "" {
+		root = "/"
+	}
+
+	if perms.Owner != "" {
+		uid, err := lookupID(filepath.Join(root, "etc", "passwd"), "user", perms.Owner)
+		if err != nil {
+			return perms, err
+		}
+		perms.UID = uid
+	}
+
+	if perms.Group != "" {
+		gid, err := lookupID(filepath.Join(root, "etc", "group"), "group", perms.Group)
+		if err != nil {
+			return perms, err
+		}
+		perms.GID = gid
+	}
+	return perms, nil
+}
+
+// lookupID returns the id of the name from a file in the format of /etc/passwd or /etc/group, the id is the third
+// field of the colon separated entries
+func lookupID(path string, kind string, name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return -1, errors.Wrapf(err, "failed to open %s to resolve %s %s", path, kind, name)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || fields[0] != name {
+			continue
+		}
+
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return -1, errors.Errorf("invalid id %s of %s %s in %s", fields[2], kind, name, path)
+		}
+		return id, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return -1, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return -1, errors.Errorf("unknown %s %s, it is not listed in %s", kind, name, path)
+}
@@ -0,0 +1,67 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// validation configures a command, which validates new content before the target is replaced
+type validation struct {
+	// Validate is executed with the path of a temporary file which contains the new content, every %s of the command is
+	// replaced by the path, e.g. visudo -cf %s. The target is only replaced if the command exits with zero. The command
+	// is split at white space and is not executed by a shell.
+	Validate string
+}
+
+// validate executes the validation command for the file at path and records the command in the result. The output
+// of the command is part of the returned error, if the validation has failed.
+func (validation validation) validate(ctx context.Context, result *welfare.Result, path string) error {
+	if validation.Validate == "" {
+		return nil
+	}
+
+	if !strings.Contains(validation.Validate, "%s") {
+		return errors.Errorf("validation command %s does not contain %%s", validation.Validate)
+	}
+
+	args := strings.Fields(validation.Validate)
+	for i, arg := range args {
+		args[i] = strings.Replace(arg, "%s", path, -1)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	command := welfare.Command{
+		Args:   args,
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+	if err != nil {
+		command.ExitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				command.ExitCode = status.ExitStatus()
+			}
+		}
+	}
+	result.Commands = append(result.Commands, command)
+
+	if err != nil {
+		output := strings.TrimSpace(command.Stderr + command.Stdout)
+		if output == "" {
+			return errors.Wrapf(err, "validation with %s failed", args[0])
+		}
+		return errors.Errorf("validation with %s failed: %s: %s", args[0], err, output)
+	}
+	return nil
+}
@@ -0,0 +1,564 @@
+package files
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+// NewXMLModule creates a new XMLModule, which ensures the elements selected by the xpath in the file at path. If the
+// xpath ends with an attribute or text() step, the attribute or the text is set to the value.
+func NewXMLModule(path string, xpath string, value string) *XMLModule {
+	module := &XMLModule{
+		Path:  path,
+		XPath: xpath,
+		Value: value,
+	}
+	module.FileMode = os.FileMode(0)
+	module.UID = -1
+	module.GID = -1
+	return module
+}
+
+// XMLModule ensures an element, an attribute or a text of a XML file, which is selected by an xpath expression. Only
+// the changed parts of the file are rewritten, the formatting, comments and every other part of the file are left
+// untouched. Mode and owner of an existing file are kept, unless they are declared by the module.
+type XMLModule struct {
+	permissions
+	replaceOptions
+	Path string
+	// XPath is an absolute path of elements with the child (/) or descendant (//) axis, it may end with an attribute
+	// (@name) or text (text()) step. Elements may be filtered by the predicates [n], [@name], [@name='value'],
+	// [child='value'] and [text()='value']. Missing elements are created, if their path contains only the child axis and
+	// predicates which compare values, e.g. /server-config/connector[@port='8080']/@protocol.
+	XPath string
+	// Value is the value of the attribute or text, which is selected by the xpath
+	Value string
+	// Absent removes the selected elements, attributes or texts
+	Absent bool
+	// Create creates the file with the document element of the xpath if it is missing, otherwise a missing file is an
+	// error
+	Create bool
+}
+
+func (module *XMLModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would change the file, without touching it
+func (module *XMLModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the xpath in the file, in check mode it only reports what would change
+func (module *XMLModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *XMLModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	steps, err := parseXPath(module.XPath)
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := module.permissions.resolve()
+	if err != nil {
+		return false, err
+	}
+
+	missing := failMissing
+	if module.Absent {
+		missing = ignoreMissing
+	} else if module.Create {
+		missing = createMissing
+	}
+
+	return editFile(ctx, result, module.Path, perms, module.replaceOptions, missing, func(current string) (string, string, error) {
+		document, err := parseXML(current)
+		if err != nil {
+			return current, "", errors.Wrapf(err, "failed to parse %s", module.Path)
+		}
+
+		editor := &xmlEditor{content: current, value: module.Value, unit: indentUnit(current, document)}
+		elements, final := steps, xpathStep{kind: elementStep}
+		if last := steps[len(steps)-1]; last.kind != elementStep {
+			elements, final = steps[:len(steps)-1], last
+		}
+
+		if module.Absent {
+			err = editor.remove(document, elements, final)
+		} else {
+			err = editor.ensure(document, elements, final)
+		}
+		if err != nil || len(editor.splices) == 0 {
+			return current, "", errors.Wrapf(err, "failed to edit %s of %s", module.XPath, module.Path)
+		}
+		if module.Absent {
+			return editor.apply(), "removed " + module.XPath + " from", nil
+		}
+		return editor.apply(), "set " + module.XPath + " in", nil
+	}, check)
+}
+
+// xmlElement is an element of a parsed XML document, it knows the byte ranges of its tags within the document
+type xmlElement struct {
+	parent   *xmlElement
+	children []*xmlElement
+	// name is the raw name of the element including its prefix, e.g. xsl:template
+	name       string
+	attributes []xml.Attr
+	// text is the unescaped character data of the element, without the text of its children
+	text string
+	// start and startEnd are the range of the start tag, end and endEnd the range of the end tag. The range of the end
+	// tag is empty, if the element is closed by its start tag.
+	start, startEnd int
+	end, endEnd     int
+}
+
+// parseXML parses the content into a tree of elements. The returned document node is the parent of the document
+// element, it has no children if the content is empty.
+func parseXML(content string) (*xmlElement, error) {
+	document := &xmlElement{end: len(content), endEnd: len(content)}
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	current := document
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := int(decoder.InputOffset())
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{
+				parent:     current,
+				name:       rawXMLName(token.Name),
+				attributes: token.Copy().Attr,
+				start:      start,
+				startEnd:   end,
+			}
+			current.children = append(current.children, element)
+			current = element
+		case xml.EndElement:
+			if current == document || rawXMLName(token.Name) != current.name {
+				return nil, errors.Errorf("unexpected end element %s", rawXMLName(token.Name))
+			}
+			current.end, current.endEnd = start, end
+			current = current.parent
+		case xml.CharData:
+			if current != document {
+				current.text += string(token)
+			}
+		}
+	}
+
+	if current != document {
+		return nil, errors.Errorf("element %s is not closed", current.name)
+	}
+	if len(document.children) > 1 {
+		return nil, errors.New("document has more than one document element")
+	}
+	return document, nil
+}
+
+func rawXMLName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func (element *xmlElement) attribute(name string) (string, bool) {
+	for _, attribute := range element.attributes {
+		if rawXMLName(attribute.Name) == name {
+			return attribute.Value, true
+		}
+	}
+	return "", false
+}
+
+// descendants returns every descendant of the element in document order
+func (element *xmlElement) descendants() []*xmlElement {
+	descendants := []*xmlElement{}
+	for _, child := range element.children {
+		descendants = append(descendants, child)
+		descendants = append(descendants, child.descendants()...)
+	}
+	return descendants
+}
+
+// selfClosing reports whether the element is closed by its start tag, e.g. <connector/>
+func (element *xmlElement) selfClosing() bool {
+	return element.end == element.endEnd
+}
+
+// xmlSplice replaces the range from start to end of the content with the text
+type xmlSplice struct {
+	start, end int
+	text       string
+}
+
+// xmlEditor collects the changes of a XML document as splices of its content, so that the untouched parts of the
+// document are kept byte by byte
+type xmlEditor struct {
+	content string
+	value   string
+	// unit is the indentation of a child relative to its parent
+	unit    string
+	splices []xmlSplice
+}
+
+// splice adds a change of the content, a change of the same range is only added once, because elements may be selected
+// more than once by the descendant axis
+func (editor *xmlEditor) splice(start, end int, text string) {
+	for _, splice := range editor.splices {
+		if splice.start == start && splice.end == end {
+			return
+		}
+	}
+	editor.splices = append(editor.splices, xmlSplice{start: start, end: end, text: text})
+}
+
+// apply returns the content with every splice applied
+func (editor *xmlEditor) apply() string {
+	sort.SliceStable(editor.splices, func(i, j int) bool {
+		return editor.splices[i].start > editor.splices[j].start
+	})
+	content := editor.content
+	for _, splice := range editor.splices {
+		content = content[:splice.start] + splice.text + content[splice.end:]
+	}
+	return content
+}
+
+// ensure walks the element steps from the node, missing elements are created. The final step is applied to every
+// selected element.
+func (editor *xmlEditor) ensure(node *xmlElement, steps []xpathStep, final xpathStep) error {
+	if len(steps) == 0 {
+		switch final.kind {
+		case attributeStep:
+			editor.setAttribute(node, final.name)
+		case textStep:
+			return editor.setText(node, editor.value)
+		}
+		return nil
+	}
+
+	matches := steps[0].match(node)
+	if len(matches) == 0 {
+		return editor.create(node, steps, final)
+	}
+	for _, match := range matches {
+		err := editor.ensure(match, steps[1:], final)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the selected elements or the final attribute or text of the selected elements
+func (editor *xmlEditor) remove(document *xmlElement, steps []xpathStep, final xpathStep) error {
+	nodes := []*xmlElement{document}
+	for _, step := range steps {
+		matches := []*xmlElement{}
+		for _, node := range nodes {
+			matches = append(matches, step.match(node)...)
+		}
+		nodes = matches
+	}
+
+	selected := map[*xmlElement]bool{}
+	for _, node := range nodes {
+		selected[node] = true
+	}
+
+	for _, node := range nodes {
+		switch final.kind {
+		case attributeStep:
+			if span, ok := attributeSpans(editor.content, node)[final.name]; ok {
+				editor.splice(span.start, span.end, "")
+			}
+		case textStep:
+			err := editor.setText(node, "")
+			if err != nil {
+				return err
+			}
+		default:
+			if node.parent == document {
+				return errors.New("the document element can not be removed")
+			}
+			if !hasSelectedAncestor(node, selected) {
+				editor.removeElement(node)
+			}
+		}
+	}
+	return nil
+}
+
+func hasSelectedAncestor(element *xmlElement, selected map[*xmlElement]bool) bool {
+	for parent := element.parent; parent != nil; parent = parent.parent {
+		if selected[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// removeElement removes the element together with the whitespace in front of it, so that its line disappears
+func (editor *xmlEditor) removeElement(element *xmlElement) {
+	start := element.start
+	for start > 0 && strings.IndexByte(" \t\r\n", editor.content[start-1]) >= 0 {
+		start--
+	}
+	editor.splice(start, element.endEnd, "")
+}
+
+func (editor *xmlEditor) setAttribute(element *xmlElement, name string) {
+	escaped := escapeXML(editor.value, true)
+	if span, ok := attributeSpans(editor.content, element)[name]; ok {
+		if value, _ := element.attribute(name); value != editor.value {
+			editor.splice(span.valueStart, span.valueEnd, escaped)
+		}
+		return
+	}
+	position := startTagEnd(editor.content, element)
+	editor.splice(position, position, " "+name+"=\""+escaped+"\"")
+}
+
+func (editor *xmlEditor) setText(element *xmlElement, text string) error {
+	if element.text == text {
+		return nil
+	}
+	if len(element.children) > 0 {
+		return errors.Errorf("text of element %s can not be set, because it contains elements", element.name)
+	}
+	if element.selfClosing() {
+		position := startTagEnd(editor.content, element)
+		editor.splice(position, element.startEnd, ">"+escapeXML(text, false)+"</"+element.name+">")
+		return nil
+	}
+	editor.splice(element.startEnd, element.end, escapeXML(text, false))
+	return nil
+}
+
+// create creates the elements of the steps as last child of the parent, the new elements are indented like their
+// siblings
+func (editor *xmlEditor) create(parent *xmlElement, steps []xpathStep, final xpathStep) error {
+	if parent.parent == nil && len(parent.children) > 0 {
+		return errors.Errorf("document element %s does not match %s", parent.children[0].name, steps[0].name)
+	}
+
+	parentIndent, pretty := linePrefix(editor.content, parent.start)
+	childIndent := parentIndent + editor.unit
+	if len(parent.children) > 0 {
+		childIndent, pretty = linePrefix(editor.content, parent.children[0].start)
+	}
+	if parent.parent == nil {
+		parentIndent, childIndent, pretty = "", "", true
+	}
+
+	fragment, err := editor.fragment(steps, final, childIndent, pretty)
+	if err != nil {
+		return err
+	}
+
+	if !pretty {
+		childIndent, parentIndent = "", ""
+	} else {
+		childIndent, parentIndent = "\n"+childIndent, "\n"+parentIndent
+	}
+
+	switch {
+	case parent.parent == nil:
+		prefix := ""
+		if strings.TrimSpace(editor.content) == "" {
+			prefix = xml.Header
+		} else if !strings.HasSuffix(editor.content, "\n") {
+			prefix = "\n"
+		}
+		editor.splice(len(editor.content), len(editor.content), prefix+fragment+"\n")
+	case len(parent.children) > 0:
+		last := parent.children[len(parent.children)-1]
+		editor.splice(last.endEnd, last.endEnd, childIndent+fragment)
+	case parent.selfClosing():
+		position := startTagEnd(editor.content, parent)
+		editor.splice(position, parent.startEnd, ">"+childIndent+fragment+parentIndent+"</"+parent.name+">")
+	case strings.TrimSpace(editor.content[parent.startEnd:parent.end]) == "":
+		editor.splice(parent.startEnd, parent.end, childIndent+fragment+parentIndent)
+	default:
+		// the element contains text, the new element is appended to the text
+		editor.splice(parent.end, parent.end, fragment)
+	}
+	return nil
+}
+
+// fragment returns the markup of the elements of the steps, the values of the predicates and the final step are added
+// to the new elements
+func (editor *xmlEditor) fragment(steps []xpathStep, final xpathStep, indent string, pretty bool) (string, error) {
+	step := steps[0]
+	if step.descendant || step.name == "*" {
+		return "", errors.Errorf("element %s can not be created, because its name is unknown", step.name)
+	}
+
+	attributes := []string{}
+	children := []string{}
+	text := ""
+	for _, predicate := range step.predicates {
+		switch {
+		case predicate.position == 1:
+			// the new element is the first one
+		case predicate.attribute != "" && predicate.hasValue:
+			attributes = append(attributes, predicate.attribute+"=\""+escapeXML(predicate.value, true)+"\"")
+		case predicate.text:
+			text = predicate.value
+		case predicate.child != "" && predicate.hasValue:
+			child := "<" + predicate.child + ">" + escapeXML(predicate.value, false) + "</" + predicate.child + ">"
+			children = append(children, child)
+		default:
+			return "", errors.Errorf("element %s can not be created, because its predicates do not declare values", step.name)
+		}
+	}
+
+	if len(steps) > 1 {
+		child, err := editor.fragment(steps[1:], final, indent+editor.unit, pretty)
+		if err != nil {
+			return "", err
+		}
+		children = append(children, child)
+	} else if final.kind == attributeStep {
+		attributes = append(attributes, final.name+"=\""+escapeXML(editor.value, true)+"\"")
+	} else if final.kind == textStep {
+		text = editor.value
+	}
+
+	if text != "" && len(children) > 0 {
+		return "", errors.Errorf("element %s can not be created with text and elements", step.name)
+	}
+
+	markup := "<" + strings.Join(append([]string{step.name}, attributes...), " ")
+	switch {
+	case len(children) > 0:
+		separator, closing := "", ""
+		if pretty {
+			separator, closing = "\n"+indent+editor.unit, "\n"+indent
+		}
+		markup += ">" + separator + strings.Join(children, separator) + closing + "</" + step.name + ">"
+	case text != "":
+		markup += ">" + escapeXML(text, false) + "</" + step.name + ">"
+	default:
+		markup += "/>"
+	}
+	return markup, nil
+}
+
+// indentUnit returns the indentation of the first indented child relative to its parent, it defaults to two spaces
+func indentUnit(content string, element *xmlElement) string {
+	for _, child := range element.children {
+		parentIndent, parentOk := linePrefix(content, element.start)
+		childIndent, childOk := linePrefix(content, child.start)
+		if element.parent != nil && parentOk && childOk && len(childIndent) > len(parentIndent) &&
+			strings.HasPrefix(childIndent, parentIndent) {
+			return childIndent[len(parentIndent):]
+		}
+		if unit := indentUnit(content, child); unit != "  " {
+			return unit
+		}
+	}
+	return "  "
+}
+
+// linePrefix returns the whitespace between the beginning of the line and the position, it reports false if the
+// position is not the first non whitespace character of its line
+func linePrefix(content string, position int) (string, bool) {
+	start := strings.LastIndexByte(content[:position], '\n') + 1
+	prefix := content[start:position]
+	if strings.TrimLeft(prefix, " \t") != "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// xmlAttributeSpan is the range of an attribute within a start tag, the range starts with the whitespace in front of
+// the attribute
+type xmlAttributeSpan struct {
+	start, end           int
+	valueStart, valueEnd int
+}
+
+// attributeSpans returns the ranges of the attributes of the element by their raw name
+func attributeSpans(content string, element *xmlElement) map[string]xmlAttributeSpan {
+	spans := map[string]xmlAttributeSpan{}
+	i := element.start + 1 + len(element.name)
+	for i < element.startEnd {
+		start := i
+		for i < element.startEnd && isXMLSpace(content[i]) {
+			i++
+		}
+		nameStart := i
+		for i < element.startEnd && !isXMLSpace(content[i]) && strings.IndexByte("=/>", content[i]) < 0 {
+			i++
+		}
+		if nameStart == i {
+			break
+		}
+		name := content[nameStart:i]
+		for i < element.startEnd && (isXMLSpace(content[i]) || content[i] == '=') {
+			i++
+		}
+		if i >= element.startEnd {
+			break
+		}
+		quote := content[i]
+		valueEnd := strings.IndexByte(content[i+1:element.startEnd], quote)
+		if valueEnd < 0 {
+			break
+		}
+		valueEnd += i + 1
+		spans[name] = xmlAttributeSpan{start: start, end: valueEnd + 1, valueStart: i + 1, valueEnd: valueEnd}
+		i = valueEnd + 1
+	}
+	return spans
+}
+
+// startTagEnd returns the position after the name and the last attribute of the start tag, new attributes and the
+// closing of self closing elements are inserted at this position
+func startTagEnd(content string, element *xmlElement) int {
+	position := element.start + 1 + len(element.name)
+	for _, span := range attributeSpans(content, element) {
+		if span.end > position {
+			position = span.end
+		}
+	}
+	return position
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// escapeXML escapes the value for the content or, if attribute is true, the quoted attribute value of an element
+func escapeXML(value string, attribute bool) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	if attribute {
+		replacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;",
+			"\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+	}
+	return replacer.Replace(value)
+}
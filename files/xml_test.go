@@ -0,0 +1,203 @@
+package files_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const serverConfig = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- configuration of the scm-server -->
+<server-config>
+    <context-path>/scm</context-path>
+    <connector port="8080" host="0.0.0.0"/>
+    <connector port="8443" protocol="https">
+        <keystore>conf/keystore.jks</keystore>
+    </connector>
+    <ajp enabled="false"></ajp>
+</server-config>
+`
+
+func TestXMLModule_ExecuteSetsText(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", serverConfig)
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "/server-config/context-path/text()", "/")
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.Equal(t, "set /server-config/context-path/text() in "+target, result.Message)
+	assert.Contains(t, result.Diff, "-    <context-path>/scm</context-path>\n+    <context-path>/</context-path>\n")
+
+	result, err = module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestXMLModule_ExecuteSetsAttributes(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", serverConfig)
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "/server-config/connector[@port='8080']/@host", "127.0.0.1")
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/connector[1]/@protocol", "http & ajp")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "//ajp/@enabled", "true")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `<?xml version="1.0" encoding="UTF-8"?>
+<!-- configuration of the scm-server -->
+<server-config>
+    <context-path>/scm</context-path>
+    <connector port="8080" host="127.0.0.1" protocol="http &amp; ajp"/>
+    <connector port="8443" protocol="https">
+        <keystore>conf/keystore.jks</keystore>
+    </connector>
+    <ajp enabled="true"></ajp>
+</server-config>
+`, target)
+}
+
+func TestXMLModule_ExecuteCreatesElements(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", serverConfig)
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "/server-config/connector[@port='8443']/truststore/text()", "conf/trust.jks")
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/connector[@port='8009']/@protocol", "ajp")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/ajp/secret/text()", "s3cr3t")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/logging[level='INFO']/appender", "")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `<?xml version="1.0" encoding="UTF-8"?>
+<!-- configuration of the scm-server -->
+<server-config>
+    <context-path>/scm</context-path>
+    <connector port="8080" host="0.0.0.0"/>
+    <connector port="8443" protocol="https">
+        <keystore>conf/keystore.jks</keystore>
+        <truststore>conf/trust.jks</truststore>
+    </connector>
+    <ajp enabled="false">
+        <secret>s3cr3t</secret>
+    </ajp>
+    <connector port="8009" protocol="ajp"/>
+    <logging>
+        <level>INFO</level>
+        <appender/>
+    </logging>
+</server-config>
+`, target)
+}
+
+func TestXMLModule_ExecuteCreatesElementInSelfClosingElement(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", "<config>\n\t<users/>\n</config>\n")
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "/config/users/user[@name='scmadmin']", "")
+	_, err := module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, "<config>\n\t<users>\n\t\t<user name=\"scmadmin\"/>\n\t</users>\n</config>\n", target)
+}
+
+func TestXMLModule_ExecuteWithAbsent(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", serverConfig)
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "/server-config/connector[@port='8443']", "")
+	module.Absent = true
+	result, err := module.Execute(context.Background(), false)
+	require.Nil(t, err)
+	assert.Equal(t, "removed /server-config/connector[@port='8443'] from "+target, result.Message)
+
+	module = files.NewXMLModule(target, "/server-config/connector/@host", "")
+	module.Absent = true
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/context-path/text()", "")
+	module.Absent = true
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `<?xml version="1.0" encoding="UTF-8"?>
+<!-- configuration of the scm-server -->
+<server-config>
+    <context-path></context-path>
+    <connector port="8080"/>
+    <ajp enabled="false"></ajp>
+</server-config>
+`, target)
+
+	module = files.NewXMLModule(target, "/server-config/connector[@port='8443']", "")
+	module.Absent = true
+	result, err = module.Execute(context.Background(), false)
+	require.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+}
+
+func TestXMLModule_ExecuteWithCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xml")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := path.Join(dir, "server-config.xml")
+	module := files.NewXMLModule(target, "/server-config/context-path/text()", "/scm")
+	_, err = module.Run()
+	assert.EqualError(t, err, target+" does not exist")
+
+	module.Create = true
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	assertContent(t, `<?xml version="1.0" encoding="UTF-8"?>
+<server-config>
+  <context-path>/scm</context-path>
+</server-config>
+`, target)
+}
+
+func TestXMLModule_ExecuteWithInvalidXPath(t *testing.T) {
+	target, cleanup := createFile(t, "server-config.xml", serverConfig)
+	defer cleanup()
+
+	module := files.NewXMLModule(target, "server-config/context-path", "")
+	_, err := module.Run()
+	assert.EqualError(t, err, "invalid xpath server-config/context-path, only absolute paths are supported")
+
+	module = files.NewXMLModule(target, "/server-config/@port/text()", "")
+	_, err = module.Run()
+	assert.EqualError(t, err, "invalid xpath /server-config/@port/text(), attributes and text must be the last step")
+
+	module = files.NewXMLModule(target, "/server-config/connector[@port]/@host", "")
+	_, err = module.Run()
+	require.Nil(t, err)
+
+	module = files.NewXMLModule(target, "/server-config/proxy[@port]", "")
+	_, err = module.Run()
+	assert.EqualError(t, err, "failed to edit /server-config/proxy[@port] of "+target+
+		": element proxy can not be created, because its predicates do not declare values")
+}
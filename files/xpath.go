@@ -0,0 +1,267 @@
+package files
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type xpathStepKind int
+
+const (
+	elementStep xpathStepKind = iota
+	attributeStep
+	textStep
+)
+
+// xpathStep is a single location step of an xpath expression, e.g. connector[@port='8080']
+type xpathStep struct {
+	kind xpathStepKind
+	// descendant selects every descendant instead of only the children, it is declared by //
+	descendant bool
+	// name is the raw name including the prefix of the element or attribute, * matches every element
+	name       string
+	predicates []xpathPredicate
+}
+
+// xpathPredicate filters the elements of a step, e.g. [2], [@port='8080'], [name='scm'] or [text()='scm']
+type xpathPredicate struct {
+	// position selects the nth element, it is zero if the predicate compares a value
+	position  int
+	attribute string
+	child     string
+	text      bool
+	value     string
+	hasValue  bool
+}
+
+// parseXPath parses the supported subset of xpath. An expression is an absolute path of element steps with the child
+// (/) or descendant (//) axis, which may end with an attribute (@name) or a text (text()) step. Element steps support
+// the predicates [n], [@name], [@name='value'], [child='value'] and [text()='value'].
+func parseXPath(expression string) ([]xpathStep, error) {
+	if !strings.HasPrefix(expression, "/") {
+		return nil, errors.Errorf("invalid xpath %s, only absolute paths are supported", expression)
+	}
+
+	steps := []xpathStep{}
+	for i := 0; i < len(expression); {
+		descendant := false
+		i++
+		if i < len(expression) && expression[i] == '/' {
+			descendant = true
+			i++
+		}
+
+		end := stepEnd(expression, i)
+		step, err := parseStep(expression[i:end], descendant)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid xpath %s", expression)
+		}
+		steps = append(steps, step)
+		i = end
+	}
+
+	for i, step := range steps {
+		if step.kind != elementStep && i < len(steps)-1 {
+			return nil, errors.Errorf("invalid xpath %s, attributes and text must be the last step", expression)
+		}
+	}
+	if steps[0].kind != elementStep {
+		return nil, errors.Errorf("invalid xpath %s, the path must select at least one element", expression)
+	}
+	return steps, nil
+}
+
+// stepEnd returns the index of the slash, which ends the step starting at start
+func stepEnd(expression string, start int) int {
+	depth := 0
+	quote := byte(0)
+	for i := start; i < len(expression); i++ {
+		switch c := expression[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	return len(expression)
+}
+
+func parseStep(value string, descendant bool) (xpathStep, error) {
+	step := xpathStep{descendant: descendant}
+	switch {
+	case value == "text()":
+		step.kind = textStep
+	case strings.HasPrefix(value, "@"):
+		step.kind = attributeStep
+		step.name = value[1:]
+	default:
+		step.kind = elementStep
+		step.name = value
+		if index := strings.IndexByte(value, '['); index >= 0 {
+			step.name = value[:index]
+			predicates, err := parsePredicates(value[index:])
+			if err != nil {
+				return step, err
+			}
+			step.predicates = predicates
+		}
+	}
+
+	if step.kind != elementStep && descendant {
+		return step, errors.Errorf("step %s does not support //", value)
+	}
+	if step.kind != textStep && !validXMLName(step.name) && step.name != "*" {
+		return step, errors.Errorf("step %s has an invalid name", value)
+	}
+	return step, nil
+}
+
+func parsePredicates(value string) ([]xpathPredicate, error) {
+	predicates := []xpathPredicate{}
+	for value != "" {
+		if value[0] != '[' {
+			return nil, errors.Errorf("unexpected %s", value)
+		}
+		closing := predicateEnd(value)
+		if closing < 0 {
+			return nil, errors.Errorf("predicate %s is not closed", value)
+		}
+
+		predicate, err := parsePredicate(strings.TrimSpace(value[1:closing]))
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+		value = value[closing+1:]
+	}
+	return predicates, nil
+}
+
+// predicateEnd returns the index of the bracket, which closes the predicate at the beginning of the value, or -1
+func predicateEnd(value string) int {
+	quote := byte(0)
+	for i := 1; i < len(value); i++ {
+		switch c := value[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parsePredicate(value string) (xpathPredicate, error) {
+	predicate := xpathPredicate{}
+	if position, err := strconv.Atoi(value); err == nil {
+		if position < 1 {
+			return predicate, errors.Errorf("position %d is less than 1", position)
+		}
+		predicate.position = position
+		return predicate, nil
+	}
+
+	subject := value
+	if index := strings.IndexByte(value, '='); index >= 0 {
+		subject = strings.TrimSpace(value[:index])
+		literal := strings.TrimSpace(value[index+1:])
+		if len(literal) < 2 || (literal[0] != '\'' && literal[0] != '"') || literal[len(literal)-1] != literal[0] {
+			return predicate, errors.Errorf("predicate [%s] must compare with a quoted value", value)
+		}
+		predicate.value = literal[1 : len(literal)-1]
+		predicate.hasValue = true
+	}
+
+	switch {
+	case subject == "text()":
+		if !predicate.hasValue {
+			return predicate, errors.Errorf("predicate [%s] must compare the text with a value", value)
+		}
+		predicate.text = true
+	case strings.HasPrefix(subject, "@") && validXMLName(subject[1:]):
+		predicate.attribute = subject[1:]
+	case validXMLName(subject):
+		predicate.child = subject
+	default:
+		return predicate, errors.Errorf("unsupported predicate [%s]", value)
+	}
+	return predicate, nil
+}
+
+func validXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || c == ':' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c > 127
+		if !letter && (i == 0 || !(c == '-' || c == '.' || c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the elements, which are selected by the step relative to the node
+func (step xpathStep) match(node *xmlElement) []*xmlElement {
+	candidates := node.children
+	if step.descendant {
+		candidates = node.descendants()
+	}
+
+	matches := []*xmlElement{}
+	for _, candidate := range candidates {
+		if step.name == "*" || candidate.name == step.name {
+			matches = append(matches, candidate)
+		}
+	}
+
+	for _, predicate := range step.predicates {
+		if predicate.position > 0 {
+			if predicate.position > len(matches) {
+				return nil
+			}
+			matches = matches[predicate.position-1 : predicate.position]
+			continue
+		}
+
+		filtered := []*xmlElement{}
+		for _, element := range matches {
+			if predicate.matches(element) {
+				filtered = append(filtered, element)
+			}
+		}
+		matches = filtered
+	}
+	return matches
+}
+
+func (predicate xpathPredicate) matches(element *xmlElement) bool {
+	switch {
+	case predicate.text:
+		return element.text == predicate.value
+	case predicate.attribute != "":
+		value, ok := element.attribute(predicate.attribute)
+		return ok && (!predicate.hasValue || value == predicate.value)
+	default:
+		for _, child := range element.children {
+			if child.name == predicate.child && (!predicate.hasValue || child.text == predicate.value) {
+				return true
+			}
+		}
+		return false
+	}
+}
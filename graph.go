@@ -0,0 +1,112 @@
+package welfare
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// taskGraph is the dependency graph of tasks, the edges are declared by the Requires field of the tasks
+type taskGraph struct {
+	tasks      []Task
+	requires   [][]int
+	dependents [][]int
+}
+
+// newTaskGraph creates the dependency graph of the tasks. A required task must be part of known, but requirements
+// which are not part of tasks are ignored, because they are not selected for the execution. If a name is used by more
+// than one task, the requirement refers to all of them.
+func newTaskGraph(tasks []Task, known []Task) (*taskGraph, error) {
+	knownNames := map[string]bool{}
+	for _, task := range known {
+		knownNames[task.Name] = true
+	}
+
+	indices := map[string][]int{}
+	for index, task := range tasks {
+		indices[task.Name] = append(indices[task.Name], index)
+	}
+
+	graph := &taskGraph{
+		tasks:      tasks,
+		requires:   make([][]int, len(tasks)),
+		dependents: make([][]int, len(tasks)),
+	}
+
+	for index, task := range tasks {
+		for _, name := range task.Requires {
+			if !knownNames[name] {
+				return nil, errors.Errorf("task %s requires unknown task %s", task.Name, name)
+			}
+
+			for _, required := range indices[name] {
+				graph.requires[index] = append(graph.requires[index], required)
+				graph.dependents[required] = append(graph.dependents[required], index)
+			}
+		}
+	}
+
+	return graph, graph.detectCycle()
+}
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// detectCycle returns an error which describes the first cycle of the graph
+func (graph *taskGraph) detectCycle() error {
+	marks := make([]int, len(graph.tasks))
+	path := []int{}
+
+	var visit func(index int) error
+	visit = func(index int) error {
+		marks[index] = visiting
+		path = append(path, index)
+
+		for _, required := range graph.requires[index] {
+			switch marks[required] {
+			case visiting:
+				return graph.cycleError(path, required)
+			case unvisited:
+				err := visit(required)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		marks[index] = visited
+		return nil
+	}
+
+	for index := range graph.tasks {
+		if marks[index] == unvisited {
+			err := visit(index)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (graph *taskGraph) cycleError(path []int, start int) error {
+	names := []string{}
+	for i := len(path) - 1; i >= 0; i-- {
+		names = append(names, graph.tasks[path[i]].Name)
+		if path[i] == start {
+			break
+		}
+	}
+
+	// the path is walked from the dependent to the required task, reverse it to show the order of requirements
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	names = append(names, graph.tasks[start].Name)
+
+	return errors.Errorf("dependency cycle detected: %s", strings.Join(names, " -> "))
+}
@@ -1,6 +1,59 @@
 package welfare
 
+import "context"
+
 // Module represents a single declarative module
 type Module interface {
+	// Execute ensures the declared state and describes the outcome as Result. If check is true, the module must not
+	// change the system, but has to report the outcome it would have. The module has to stop as soon as possible, if
+	// the context is done.
+	Execute(ctx context.Context, check bool) (*Result, error)
+}
+
+// LegacyModule is the former contract of a module, which reports only whether the system was changed
+type LegacyModule interface {
+	// Run ensures the declared state and reports whether the system was changed
 	Run() (changed bool, err error)
 }
+
+// LegacyChecker is implemented by legacy modules, which are able to report changes without changing the system
+type LegacyChecker interface {
+	// Check reports whether Run would change the system, without changing anything
+	Check() (changed bool, err error)
+}
+
+// Legacy wraps a LegacyModule, so that it can be used as Module. In check mode the wrapped module is skipped, unless
+// it implements LegacyChecker. The context is only checked before the wrapped module is called.
+func Legacy(module LegacyModule) Module {
+	return &legacyModule{module}
+}
+
+// Report converts the outcome of Execute to the legacy (changed, err) signature
+func Report(result *Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	return result.Changed(), nil
+}
+
+type legacyModule struct {
+	module LegacyModule
+}
+
+func (legacy *legacyModule) Execute(ctx context.Context, check bool) (*Result, error) {
+	result := NewResult()
+	if err := ctx.Err(); err != nil {
+		return result.Finish(false, err)
+	}
+
+	if check {
+		checker, ok := legacy.module.(LegacyChecker)
+		if !ok {
+			result.Status = Skipped
+			result.Message = "module does not support check mode"
+			return result, nil
+		}
+		return result.Finish(checker.Check())
+	}
+	return result.Finish(legacy.module.Run())
+}
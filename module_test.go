@@ -0,0 +1,74 @@
+package welfare_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacy(t *testing.T) {
+	result, err := welfare.Legacy(&legacyModule{changed: true}).Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+}
+
+func TestLegacyWithError(t *testing.T) {
+	result, err := welfare.Legacy(&legacyModule{err: errors.New("failed")}).Execute(context.Background(), false)
+	assert.Error(t, err)
+	assert.Equal(t, welfare.Failed, result.Status)
+	assert.Equal(t, "failed", result.Message)
+}
+
+func TestLegacyInCheckMode(t *testing.T) {
+	result, err := welfare.Legacy(&legacyModule{changed: true}).Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Skipped, result.Status)
+}
+
+func TestLegacyWithCheckerInCheckMode(t *testing.T) {
+	result, err := welfare.Legacy(&legacyChecker{}).Execute(context.Background(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+}
+
+func TestLegacyWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := welfare.Legacy(&legacyModule{changed: true}).Execute(ctx, false)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, welfare.Failed, result.Status)
+}
+
+func TestReport(t *testing.T) {
+	changed, err := welfare.Report(&welfare.Result{Status: welfare.Changed}, nil)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	changed, err = welfare.Report(&welfare.Result{Status: welfare.OK}, nil)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	_, err = welfare.Report(&welfare.Result{Status: welfare.Failed}, errors.New("failed"))
+	assert.Error(t, err)
+}
+
+type legacyModule struct {
+	changed bool
+	err     error
+}
+
+func (module *legacyModule) Run() (bool, error) {
+	return module.changed, module.err
+}
+
+type legacyChecker struct {
+	legacyModule
+}
+
+func (module *legacyChecker) Check() (bool, error) {
+	return true, nil
+}
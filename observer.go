@@ -0,0 +1,192 @@
+package welfare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Observer is notified by the runner about the lifecycle of tasks and handlers. Every started task is finished, but
+// tasks which are skipped because a required task has failed are finished without being started. Between start and
+// finish the observer is notified if the task was skipped, has changed the system or has failed. The runner calls the
+// observer from a single goroutine, even if tasks are executed concurrently.
+type Observer interface {
+	// TaskStarted is called before the module of the task is executed
+	TaskStarted(task Task)
+	// TaskSkipped is called if the task was skipped
+	TaskSkipped(task Task, result TaskResult)
+	// TaskChanged is called if the task has changed the system or would change it in check mode
+	TaskChanged(task Task, result TaskResult)
+	// TaskFailed is called if the task has failed
+	TaskFailed(task Task, result TaskResult)
+	// TaskFinished is called for every task, after the task specific notification
+	TaskFinished(task Task, result TaskResult)
+}
+
+// NopObserver ignores all notifications. It can be embedded by observers which are only interested in some of them.
+type NopObserver struct{}
+
+// TaskStarted does nothing
+func (NopObserver) TaskStarted(task Task) {}
+
+// TaskSkipped does nothing
+func (NopObserver) TaskSkipped(task Task, result TaskResult) {}
+
+// TaskChanged does nothing
+func (NopObserver) TaskChanged(task Task, result TaskResult) {}
+
+// TaskFailed does nothing
+func (NopObserver) TaskFailed(task Task, result TaskResult) {}
+
+// TaskFinished does nothing
+func (NopObserver) TaskFinished(task Task, result TaskResult) {}
+
+// notifyFinished notifies the observer about the outcome of a task
+func notifyFinished(observer Observer, task Task, taskResult TaskResult) {
+	switch taskResult.Result.Status {
+	case Skipped:
+		observer.TaskSkipped(task, taskResult)
+	case Changed:
+		observer.TaskChanged(task, taskResult)
+	case Failed:
+		observer.TaskFailed(task, taskResult)
+	}
+	observer.TaskFinished(task, taskResult)
+}
+
+// ConsoleObserver prints a human readable line with status and name for every finished task. The message of failed
+// tasks is always printed, a verbosity of 1 adds the messages, backups, changed paths and diffs of all tasks and a
+// verbosity of 2 adds the output of executed commands.
+type ConsoleObserver struct {
+	NopObserver
+	writer    io.Writer
+	verbosity int
+}
+
+// NewConsoleObserver creates a new console observer, which writes to the writer
+func NewConsoleObserver(writer io.Writer, verbosity int) *ConsoleObserver {
+	return &ConsoleObserver{
+		writer:    writer,
+		verbosity: verbosity,
+	}
+}
+
+// TaskFinished prints the outcome of the task
+func (observer *ConsoleObserver) TaskFinished(task Task, taskResult TaskResult) {
+	result := taskResult.Result
+	fmt.Fprintf(observer.writer, "%-8s %s\n", result.Status.String()+":", taskResult.Name)
+
+	if observer.verbosity < 1 && result.Status != Failed {
+		return
+	}
+
+	if result.Message != "" {
+		fmt.Fprintf(observer.writer, "         %s\n", result.Message)
+	}
+
+	if observer.verbosity < 1 {
+		return
+	}
+
+	if result.Backup != "" {
+		fmt.Fprintf(observer.writer, "         backup %s\n", result.Backup)
+	}
+
+	for _, path := range result.ChangedPaths {
+		fmt.Fprintf(observer.writer, "         changed %s\n", path)
+	}
+
+	if result.Diff != "" {
+		fmt.Fprint(observer.writer, result.Diff)
+	}
+
+	if observer.verbosity < 2 {
+		return
+	}
+
+	for _, command := range result.Commands {
+		fmt.Fprintf(observer.writer, "         $ %s (exit code %d)\n", strings.Join(command.Args, " "), command.ExitCode)
+		fmt.Fprint(observer.writer, command.Stdout)
+		fmt.Fprint(observer.writer, command.Stderr)
+	}
+}
+
+// JSONObserver writes every notification as a single line JSON object, e.g.:
+//
+//	{"event":"started","task":"install htop","time":"2026-10-18T12:00:00Z"}
+//	{"event":"changed","task":"install htop","time":"2026-10-18T12:00:03Z","status":"changed","message":"installed htop","duration":3.1}
+//	{"event":"finished","task":"install htop","time":"2026-10-18T12:00:03Z","status":"changed","message":"installed htop","duration":3.1}
+type JSONObserver struct {
+	encoder *json.Encoder
+}
+
+// NewJSONObserver creates a new JSON lines observer, which writes to the writer
+func NewJSONObserver(writer io.Writer) *JSONObserver {
+	return &JSONObserver{
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// jsonEvent is a single line of the JSONObserver, the duration is measured in seconds
+type jsonEvent struct {
+	Event    string    `json:"event"`
+	Task     string    `json:"task"`
+	Time     time.Time `json:"time"`
+	Status   string    `json:"status,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Diff     string    `json:"diff,omitempty"`
+	Backup   string    `json:"backup,omitempty"`
+	Paths    []string  `json:"changed_paths,omitempty"`
+	Duration float64   `json:"duration,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// TaskStarted writes a started event
+func (observer *JSONObserver) TaskStarted(task Task) {
+	observer.encoder.Encode(jsonEvent{
+		Event: "started",
+		Task:  task.Name,
+		Time:  time.Now(),
+	})
+}
+
+// TaskSkipped writes a skipped event
+func (observer *JSONObserver) TaskSkipped(task Task, result TaskResult) {
+	observer.write("skipped", result)
+}
+
+// TaskChanged writes a changed event
+func (observer *JSONObserver) TaskChanged(task Task, result TaskResult) {
+	observer.write("changed", result)
+}
+
+// TaskFailed writes a failed event
+func (observer *JSONObserver) TaskFailed(task Task, result TaskResult) {
+	observer.write("failed", result)
+}
+
+// TaskFinished writes a finished event
+func (observer *JSONObserver) TaskFinished(task Task, result TaskResult) {
+	observer.write("finished", result)
+}
+
+func (observer *JSONObserver) write(event string, taskResult TaskResult) {
+	result := taskResult.Result
+	line := jsonEvent{
+		Event:    event,
+		Task:     taskResult.Name,
+		Time:     time.Now(),
+		Status:   result.Status.String(),
+		Message:  result.Message,
+		Diff:     result.Diff,
+		Backup:   result.Backup,
+		Paths:    result.ChangedPaths,
+		Duration: result.Duration.Seconds(),
+	}
+	if taskResult.Err != nil {
+		line.Error = taskResult.Err.Error()
+	}
+	observer.encoder.Encode(line)
+}
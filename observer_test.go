@@ -0,0 +1,110 @@
+package welfare_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_RunNotifiesObserver(t *testing.T) {
+	observer := &eventObserver{}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "ok", Module: &testModule{status: welfare.OK}},
+		welfare.Task{Name: "changed", Module: &testModule{status: welfare.Changed}},
+		welfare.Task{Name: "failed", Module: &testModule{err: errors.New("failed")}},
+		welfare.Task{Name: "dependent", Module: &testModule{}, Requires: []string{"failed"}},
+	)
+	runner.KeepGoing = true
+	runner.Observer = observer
+
+	_, err := runner.Run()
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"started ok", "finished ok",
+		"started changed", "changed changed", "finished changed",
+		"started failed", "failed failed", "finished failed",
+		"skipped dependent", "finished dependent",
+	}, observer.events)
+}
+
+func TestConsoleObserver(t *testing.T) {
+	task := welfare.Task{Name: "copy"}
+	result := welfare.TaskResult{
+		Name: "copy",
+		Result: &welfare.Result{
+			Status:   welfare.Changed,
+			Message:  "copied file",
+			Diff:     "+hello\n",
+			Commands: []welfare.Command{{Args: []string{"echo", "hello"}, Stdout: "hello\n"}},
+		},
+	}
+
+	var buffer bytes.Buffer
+	welfare.NewConsoleObserver(&buffer, 0).TaskFinished(task, result)
+	assert.Equal(t, "changed: copy\n", buffer.String())
+
+	buffer.Reset()
+	welfare.NewConsoleObserver(&buffer, 2).TaskFinished(task, result)
+	assert.Equal(t, "changed: copy\n         copied file\n+hello\n         $ echo hello (exit code 0)\nhello\n", buffer.String())
+}
+
+func TestJSONObserver(t *testing.T) {
+	var buffer bytes.Buffer
+	runner := welfare.NewRunner()
+	runner.Add("one", &testModule{err: errors.New("broken")})
+	runner.Observer = welfare.NewJSONObserver(&buffer)
+
+	_, err := runner.Run()
+	assert.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3)
+
+	events := []map[string]interface{}{}
+	for _, line := range lines {
+		event := map[string]interface{}{}
+		require.Nil(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+
+	assert.Equal(t, "started", events[0]["event"])
+	assert.Equal(t, "one", events[0]["task"])
+	assert.NotContains(t, events[0], "status")
+	assert.Equal(t, "failed", events[1]["event"])
+	assert.Equal(t, "finished", events[2]["event"])
+	assert.Equal(t, "failed", events[2]["status"])
+	assert.Equal(t, "broken", events[2]["message"])
+	assert.Equal(t, "broken", events[2]["error"])
+}
+
+type eventObserver struct {
+	welfare.NopObserver
+	events []string
+}
+
+func (observer *eventObserver) TaskStarted(task welfare.Task) {
+	observer.events = append(observer.events, "started "+task.Name)
+}
+
+func (observer *eventObserver) TaskSkipped(task welfare.Task, result welfare.TaskResult) {
+	observer.events = append(observer.events, "skipped "+task.Name)
+}
+
+func (observer *eventObserver) TaskChanged(task welfare.Task, result welfare.TaskResult) {
+	observer.events = append(observer.events, "changed "+task.Name)
+}
+
+func (observer *eventObserver) TaskFailed(task welfare.Task, result welfare.TaskResult) {
+	observer.events = append(observer.events, "failed "+task.Name)
+}
+
+func (observer *eventObserver) TaskFinished(task welfare.Task, result welfare.TaskResult) {
+	observer.events = append(observer.events, "finished "+task.Name)
+}
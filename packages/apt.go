@@ -1,10 +1,14 @@
 package packages
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"os"
-	"os/exec"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewAptModule creates a new PackageModule for debian based operating systems
@@ -20,47 +24,58 @@ func NewAptModule(pkg string, state State) *PackageModule {
 type aptPackageSystem struct {
 }
 
-func (apt *aptPackageSystem) GetInfo(pkg string) packageInfo {
+func (apt *aptPackageSystem) GetInfo(ctx context.Context, pkg string) (packageInfo, error) {
 	packageInfo := packageInfo{}
 
-	_, err := exec.Command("dpkg", "-s", pkg).Output()
+	status, err := output(ctx, "dpkg", "-s", pkg)
+	if ctx.Err() != nil {
+		return packageInfo, errors.Wrapf(ctx.Err(), "failed to get status of package %s", pkg)
+	}
+
 	if err != nil {
 		packageInfo.Installed = false
 	} else {
-		// TODO read version from output
 		packageInfo.Installed = true
+		packageInfo.Version = parseVersion(status)
 	}
 
-	return packageInfo
+	return packageInfo, nil
 }
 
-func (apt *aptPackageSystem) Install(pkg string) error {
+func parseVersion(status []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return ""
+}
+
+func (apt *aptPackageSystem) Install(ctx context.Context, pkg string) ([]welfare.Command, error) {
 	env := append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
 
-	cmd := exec.Command("apt-get", "-y", "update")
-	cmd.Env = env
-	err := cmd.Run()
+	update, err := execute(ctx, env, "apt-get", "-y", "update")
 	if err != nil {
-		return errors.Wrap(err, "failed to execute package update command")
+		return []welfare.Command{update}, errors.Wrap(err, "failed to execute package update command")
 	}
 
-	cmd = exec.Command("apt-get", "-y", "install", pkg)
-	cmd.Env = env
-	err = cmd.Run()
+	install, err := execute(ctx, env, "apt-get", "-y", "install", pkg)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute package update command")
+		return []welfare.Command{update, install}, errors.Wrap(err, "failed to execute package update command")
 	}
 
-	return nil
+	return []welfare.Command{update, install}, nil
 }
 
-func (apt *aptPackageSystem) Uninstall(pkg string) error {
-	cmd := exec.Command("apt-get", "-y", "remove", pkg)
-	cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
-	err := cmd.Run()
+func (apt *aptPackageSystem) Uninstall(ctx context.Context, pkg string) ([]welfare.Command, error) {
+	env := append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+
+	remove, err := execute(ctx, env, "apt-get", "-y", "remove", pkg)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute package update command")
+		return []welfare.Command{remove}, errors.Wrap(err, "failed to execute package update command")
 	}
 
-	return nil
+	return []welfare.Command{remove}, nil
 }
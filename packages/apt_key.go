@@ -2,17 +2,17 @@ package packages
 
 import (
 	"bufio"
+	"context"
 	"io"
 
 	"strings"
 
 	"regexp"
 
-	"os/exec"
-
 	"bytes"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewAptKeyModule creates a new module for managing apt repository keys
@@ -34,19 +34,49 @@ type AptKeyModule struct {
 }
 
 func (module *AptKeyModule) Run() (bool, error) {
-	present, err := module.system.IsPresent(module.ID)
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would add or remove the key, without doing so
+func (module *AptKeyModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the state of the key, in check mode it only reports what would change
+func (module *AptKeyModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *AptKeyModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	present, err := module.system.IsPresent(ctx, module.ID)
 	if err != nil {
 		return false, err
 	}
+	result.Before = welfare.State{Exists: present}
+	result.After = result.Before
 
 	if module.State == Present && !present {
-		err := module.system.Add(module.Server, module.ID)
+		result.Message = "added key " + module.ID
+		result.After = welfare.State{Exists: true}
+		if check {
+			return true, nil
+		}
+		command, err := module.system.Add(ctx, module.Server, module.ID)
+		result.Commands = []welfare.Command{command}
 		if err != nil {
 			return false, err
 		}
 		return true, nil
 	} else if module.State == Absent && present {
-		err := module.system.Remove(module.ID)
+		result.Message = "removed key " + module.ID
+		result.After = welfare.State{}
+		if check {
+			return true, nil
+		}
+		command, err := module.system.Remove(ctx, module.ID)
+		result.Commands = []welfare.Command{command}
 		if err != nil {
 			return false, err
 		}
@@ -57,33 +87,32 @@ func (module *AptKeyModule) Run() (bool, error) {
 }
 
 type keySystem interface {
-	Add(server, key string) error
-	Remove(key string) error
-	IsPresent(key string) (bool, error)
+	Add(ctx context.Context, server, key string) (welfare.Command, error)
+	Remove(ctx context.Context, key string) (welfare.Command, error)
+	IsPresent(ctx context.Context, key string) (bool, error)
 }
 
 type aptKey struct {
 }
 
-func (sys *aptKey) Add(server string, id string) error {
-	err := exec.Command("apt-key", "adv", "--recv-keys", "--keyserver", server, id).Run()
+func (sys *aptKey) Add(ctx context.Context, server string, id string) (welfare.Command, error) {
+	command, err := execute(ctx, nil, "apt-key", "adv", "--recv-keys", "--keyserver", server, id)
 	if err != nil {
-		return errors.Wrapf(err, "failed to add key %s from server %s", id, server)
+		return command, errors.Wrapf(err, "failed to add key %s from server %s", id, server)
 	}
-	return nil
+	return command, nil
 }
 
-func (sys *aptKey) Remove(id string) error {
-	err := exec.Command("apt-key", "del", id).Run()
+func (sys *aptKey) Remove(ctx context.Context, id string) (welfare.Command, error) {
+	command, err := execute(ctx, nil, "apt-key", "del", id)
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove key %s", id)
+		return command, errors.Wrapf(err, "failed to remove key %s", id)
 	}
-	return nil
+	return command, nil
 }
 
-func (sys *aptKey) IsPresent(id string) (bool, error) {
-	cmd := exec.Command("apt-key", "list")
-	listing, err := cmd.Output()
+func (sys *aptKey) IsPresent(ctx context.Context, id string) (bool, error) {
+	listing, err := output(ctx, "apt-key", "list")
 	if err != nil {
 		return false, errors.Wrap(err, "failed to list keys")
 	}
@@ -114,5 +143,4 @@ func containsKey(listing io.Reader, key string) (bool, error) {
 		}
 
 	}
-	return false, nil
 }
@@ -1,10 +1,12 @@
 package packages
 
 import (
+	"context"
 	"testing"
 
 	"strings"
 
+	"github.com/sdorra/welfare"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -97,22 +99,67 @@ func TestAptKeyModule_RunAbsentAlreadyAbsent(t *testing.T) {
 	assert.False(t, change)
 }
 
+func TestAptKeyModule_Check(t *testing.T) {
+	sys := &testKeySystem{
+		isPresent: false,
+	}
+
+	key := NewAptKeyModule("D742B261", Present)
+	key.system = sys
+
+	change, err := key.Check()
+	assert.Nil(t, err)
+	assert.True(t, change)
+
+	assert.Equal(t, "", sys.add)
+}
+
+func TestAptKeyModule_CheckAbsent(t *testing.T) {
+	sys := &testKeySystem{
+		isPresent: true,
+	}
+
+	key := NewAptKeyModule("D742B261", Absent)
+	key.system = sys
+
+	change, err := key.Check()
+	assert.Nil(t, err)
+	assert.True(t, change)
+
+	assert.Equal(t, "", sys.remove)
+}
+
+func TestAptKeyModule_Execute(t *testing.T) {
+	sys := &testKeySystem{
+		isPresent: true,
+	}
+
+	key := NewAptKeyModule("D742B261", Present)
+	key.system = sys
+
+	result, err := key.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.OK, result.Status)
+	assert.True(t, result.Before.Exists)
+	assert.True(t, result.After.Exists)
+}
+
 type testKeySystem struct {
 	add       string
 	remove    string
 	isPresent bool
 }
 
-func (sys *testKeySystem) Add(server string, id string) error {
+func (sys *testKeySystem) Add(ctx context.Context, server string, id string) (welfare.Command, error) {
 	sys.add = id
-	return nil
+	return welfare.Command{}, nil
 }
 
-func (sys *testKeySystem) Remove(id string) error {
+func (sys *testKeySystem) Remove(ctx context.Context, id string) (welfare.Command, error) {
 	sys.remove = id
-	return nil
+	return welfare.Command{}, nil
 }
 
-func (sys *testKeySystem) IsPresent(id string) (bool, error) {
+func (sys *testKeySystem) IsPresent(ctx context.Context, id string) (bool, error) {
 	return sys.isPresent, nil
 }
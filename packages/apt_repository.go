@@ -1,6 +1,7 @@
 package packages
 
 import (
+	"context"
 	"io/ioutil"
 	"path"
 
@@ -14,6 +15,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
 )
 
 // NewAptRepositoryModule creates a new module for managing apt repositories
@@ -34,12 +36,39 @@ type AptRepositoryModule struct {
 }
 
 func (module *AptRepositoryModule) Run() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would register the repository, without doing so
+func (module *AptRepositoryModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the state of the repository, in check mode it only reports what would change
+func (module *AptRepositoryModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *AptRepositoryModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	present, err := module.isPresent()
 	if err != nil {
 		return false, err
 	}
+	result.Before = welfare.State{Exists: present}
+	result.After = result.Before
 
 	if module.State == Present && !present {
+		result.Message = "registered repository " + module.Name
+		result.After = welfare.State{Exists: true}
+		if check {
+			return true, nil
+		}
 		err := module.registerRepository()
 		if err != nil {
 			return false, err
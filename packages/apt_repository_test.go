@@ -88,3 +88,21 @@ func TestAptRepositoryModule_RunWithPresentRepositoryInSourceList(t *testing.T)
 	assert.Nil(t, err)
 	assert.False(t, changed)
 }
+
+func TestAptRepositoryModule_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apt_repository")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := packages.NewAptRepositoryModule("scm-manager", packages.Present)
+	repo.Directory = dir
+	repo.Repository = "deb http://maven.scm-manager.org/nexus/content/repositories/releases ./"
+
+	changed, err := repo.Check()
+
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	_, err = os.Stat(path.Join(dir, "sources.list.d", "scm-manager.list"))
+	assert.True(t, os.IsNotExist(err))
+}
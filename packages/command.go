@@ -0,0 +1,76 @@
+package packages
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"syscall"
+
+	"github.com/sdorra/welfare"
+)
+
+// execute runs the command with the given environment and records its output and exit code. The command is started in
+// its own process group, so that the command and all of its children are killed if the context is done.
+func execute(ctx context.Context, env []string, name string, args ...string) (welfare.Command, error) {
+	command := welfare.Command{
+		Args: append([]string{name}, args...),
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := run(ctx, cmd)
+	command.Stdout = stdout.String()
+	command.Stderr = stderr.String()
+	if err != nil {
+		command.ExitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				command.ExitCode = status.ExitStatus()
+			}
+		}
+	}
+
+	return command, err
+}
+
+// output runs the command and returns its standard output, the command is killed if the context is done
+func output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := run(ctx, cmd)
+	return stdout.Bytes(), err
+}
+
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			// exec.CommandContext kills only the command itself, children like the dpkg processes of apt-get would
+			// keep running and would block Wait, because they inherit the output pipes
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+
+	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package packages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	command, err := execute(context.Background(), nil, "sh", "-c", "echo out; echo err >&2; exit 3")
+	assert.Error(t, err)
+	assert.Equal(t, "out\n", command.Stdout)
+	assert.Equal(t, "err\n", command.Stderr)
+	assert.Equal(t, 3, command.ExitCode)
+}
+
+func TestExecuteKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// the child sleep inherits the output pipes, the command returns only if the whole group is killed
+	command, err := execute(ctx, nil, "sh", "-c", "sleep 10 & sleep 10")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, -1, command.ExitCode)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
@@ -1,5 +1,11 @@
 package packages
 
+import (
+	"context"
+
+	"github.com/sdorra/welfare"
+)
+
 // State of a package in the system
 type State int
 
@@ -18,22 +24,57 @@ type PackageModule struct {
 }
 
 func (module *PackageModule) Run() (bool, error) {
-	pkgInfo := module.system.GetInfo(module.Package)
+	return welfare.Report(module.Execute(context.Background(), false))
+}
+
+// Check reports whether Run would install or uninstall the package, without doing so
+func (module *PackageModule) Check() (bool, error) {
+	return welfare.Report(module.Execute(context.Background(), true))
+}
+
+// Execute ensures the state of the package, in check mode it only reports what would change
+func (module *PackageModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	result := welfare.NewResult()
+	changed, err := module.execute(ctx, result, check)
+	return result.Finish(changed, err)
+}
+
+func (module *PackageModule) execute(ctx context.Context, result *welfare.Result, check bool) (bool, error) {
+	pkgInfo, err := module.system.GetInfo(ctx, module.Package)
+	if err != nil {
+		return false, err
+	}
+	result.Before = pkgInfo.state()
+	result.After = result.Before
 
-	changed := false
 	if module.State == Present && !pkgInfo.Installed {
-		err := module.system.Install(module.Package)
+		result.Message = "installed package " + module.Package
+		result.After = welfare.State{Exists: true}
+		if check {
+			return true, nil
+		}
+		result.Commands, err = module.system.Install(ctx, module.Package)
 		if err != nil {
 			return false, err
 		}
-		changed = true
 	} else if module.State == Absent && pkgInfo.Installed {
-		err := module.system.Uninstall(module.Package)
+		result.Message = "uninstalled package " + module.Package
+		result.After = welfare.State{}
+		if check {
+			return true, nil
+		}
+		result.Commands, err = module.system.Uninstall(ctx, module.Package)
 		if err != nil {
 			return false, err
 		}
-		changed = true
+	} else {
+		return false, nil
 	}
 
-	return changed, nil
+	pkgInfo, err = module.system.GetInfo(ctx, module.Package)
+	if err != nil {
+		return false, err
+	}
+	result.After = pkgInfo.state()
+	return true, nil
 }
@@ -1,8 +1,10 @@
 package packages
 
 import (
+	"context"
 	"testing"
 
+	"github.com/sdorra/welfare"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -82,24 +84,91 @@ func TestPackageModule_RunAlreadyUninstalled(t *testing.T) {
 	assert.False(t, changed)
 }
 
+func TestPackageModule_Check(t *testing.T) {
+	system := &testPackageSystem{
+		info: packageInfo{
+			Installed: false,
+		},
+	}
+
+	module := PackageModule{
+		Package: "htop",
+		State:   Present,
+		system:  system,
+	}
+
+	changed, err := module.Check()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "", system.action)
+}
+
+func TestPackageModule_CheckAlreadyUninstalled(t *testing.T) {
+	system := &testPackageSystem{
+		info: packageInfo{
+			Installed: false,
+		},
+	}
+
+	module := PackageModule{
+		Package: "htop",
+		State:   Absent,
+		system:  system,
+	}
+
+	changed, err := module.Check()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "", system.action)
+}
+
+func TestPackageModule_Execute(t *testing.T) {
+	system := &testPackageSystem{
+		info: packageInfo{
+			Installed: false,
+		},
+	}
+
+	module := PackageModule{
+		Package: "htop",
+		State:   Present,
+		system:  system,
+	}
+
+	result, err := module.Execute(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, welfare.Changed, result.Status)
+	assert.False(t, result.Before.Exists)
+	assert.True(t, result.After.Exists)
+	assert.Equal(t, "1.0.0", result.After.Version)
+	assert.Equal(t, []string{"install", "htop"}, result.Commands[0].Args)
+}
+
+func TestParseVersion(t *testing.T) {
+	status := "Package: htop\nStatus: install ok installed\nVersion: 2.0.1-1ubuntu1\nDepends: libc6\n"
+	assert.Equal(t, "2.0.1-1ubuntu1", parseVersion([]byte(status)))
+}
+
 type testPackageSystem struct {
 	info   packageInfo
 	action string
 	pkg    string
 }
 
-func (system *testPackageSystem) GetInfo(pkg string) packageInfo {
-	return system.info
+func (system *testPackageSystem) GetInfo(ctx context.Context, pkg string) (packageInfo, error) {
+	return system.info, nil
 }
 
-func (system *testPackageSystem) Install(pkg string) error {
+func (system *testPackageSystem) Install(ctx context.Context, pkg string) ([]welfare.Command, error) {
 	system.action = "install"
 	system.pkg = pkg
-	return nil
+	system.info = packageInfo{Installed: true, Version: "1.0.0"}
+	return []welfare.Command{{Args: []string{"install", pkg}}}, nil
 }
 
-func (system *testPackageSystem) Uninstall(pkg string) error {
+func (system *testPackageSystem) Uninstall(ctx context.Context, pkg string) ([]welfare.Command, error) {
 	system.action = "uninstall"
 	system.pkg = pkg
-	return nil
+	system.info = packageInfo{}
+	return []welfare.Command{{Args: []string{"uninstall", pkg}}}, nil
 }
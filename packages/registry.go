@@ -0,0 +1,95 @@
+package packages
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+)
+
+func init() {
+	welfare.Register("package", newPackageModuleFromArguments)
+	welfare.Register("apt_key", newAptKeyModuleFromArguments)
+	welfare.Register("apt_repository", newAptRepositoryModuleFromArguments)
+}
+
+type packageArguments struct {
+	Name  string `welfare:"name,required"`
+	State string `welfare:"state"`
+}
+
+type aptKeyArguments struct {
+	ID     string `welfare:"id,required"`
+	Server string `welfare:"server"`
+	State  string `welfare:"state"`
+}
+
+type aptRepositoryArguments struct {
+	Name       string `welfare:"name,required"`
+	Repository string `welfare:"repository,required"`
+	State      string `welfare:"state"`
+	Directory  string `welfare:"directory"`
+}
+
+func newPackageModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := packageArguments{State: "present"}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := parseState(arguments.State)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "state", Reason: err.Error()}
+	}
+
+	return NewAptModule(arguments.Name, state), nil
+}
+
+func newAptKeyModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := aptKeyArguments{State: "present"}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := parseState(arguments.State)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "state", Reason: err.Error()}
+	}
+
+	module := NewAptKeyModule(arguments.ID, state)
+	if arguments.Server != "" {
+		module.Server = arguments.Server
+	}
+	return module, nil
+}
+
+func newAptRepositoryModuleFromArguments(args welfare.Arguments) (welfare.Module, error) {
+	arguments := aptRepositoryArguments{State: "present"}
+	err := args.Decode(&arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := parseState(arguments.State)
+	if err != nil {
+		return nil, &welfare.ArgumentError{Field: "state", Reason: err.Error()}
+	}
+
+	module := NewAptRepositoryModule(arguments.Name, state)
+	module.Repository = arguments.Repository
+	if arguments.Directory != "" {
+		module.Directory = arguments.Directory
+	}
+	return module, nil
+}
+
+func parseState(value string) (State, error) {
+	switch value {
+	case "present":
+		return Present, nil
+	case "absent":
+		return Absent, nil
+	default:
+		return Present, errors.Errorf("unknown state %s", value)
+	}
+}
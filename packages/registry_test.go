@@ -0,0 +1,33 @@
+package packages_test
+
+import (
+	"testing"
+
+	"github.com/sdorra/welfare"
+	"github.com/sdorra/welfare/packages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Package(t *testing.T) {
+	module, err := welfare.NewModule("package", welfare.Arguments{"name": "htop", "state": "absent"})
+	require.Nil(t, err)
+
+	pkg := module.(*packages.PackageModule)
+	assert.Equal(t, "htop", pkg.Package)
+	assert.Equal(t, packages.State(packages.Absent), pkg.State)
+}
+
+func TestRegistry_AptKey(t *testing.T) {
+	module, err := welfare.NewModule("apt_key", welfare.Arguments{"id": "D742B261"})
+	require.Nil(t, err)
+
+	key := module.(*packages.AptKeyModule)
+	assert.Equal(t, "D742B261", key.ID)
+	assert.Equal(t, "hkp://keyserver.ubuntu.com:80", key.Server)
+}
+
+func TestRegistry_AptRepository(t *testing.T) {
+	_, err := welfare.NewModule("apt_repository", welfare.Arguments{"name": "scm-manager"})
+	assert.EqualError(t, err, "module apt_repository: invalid argument repository: is required")
+}
@@ -1,11 +1,25 @@
 package packages
 
+import (
+	"context"
+
+	"github.com/sdorra/welfare"
+)
+
 type packageSystem interface {
-	GetInfo(pkg string) packageInfo
-	Install(pkg string) error
-	Uninstall(pkg string) error
+	GetInfo(ctx context.Context, pkg string) (packageInfo, error)
+	Install(ctx context.Context, pkg string) ([]welfare.Command, error)
+	Uninstall(ctx context.Context, pkg string) ([]welfare.Command, error)
 }
 
 type packageInfo struct {
 	Installed bool
+	Version   string
+}
+
+func (info packageInfo) state() welfare.State {
+	return welfare.State{
+		Exists:  info.Installed,
+		Version: info.Version,
+	}
 }
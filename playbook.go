@@ -0,0 +1,247 @@
+package welfare
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Playbook is a declarative description of tasks, which is read from a YAML or JSON document. The document is a list
+// of tasks and each task consists of an optional name, optional tags, optional handlers to notify, optional required
+// tasks, an optional timeout and exactly one module with its arguments, e.g.:
+//
+//	# playbook.yml
+//	- name: create config directory
+//	  tags: [config]
+//	  file: {path: /etc/welfare, state: directory, mode: "0700"}
+//	- name: install htop
+//	  requires: create config directory
+//	  timeout: 5m
+//	  package: {name: htop}
+//
+// If the playbook declares handlers, the document is a map of tasks and handlers instead:
+//
+//	tasks:
+//	  - name: configure nginx
+//	    template: {target: /etc/nginx/nginx.conf, template: "..."}
+//	    notify: request reload
+//	handlers:
+//	  - name: request reload
+//	    file: {path: /run/nginx/reload-requested, state: file}
+type Playbook struct {
+	Tasks    []Task
+	Handlers []Task
+}
+
+// Runner creates a new runner for the tasks and handlers of the playbook
+func (playbook *Playbook) Runner() *Runner {
+	runner := NewRunner(playbook.Tasks...)
+	runner.Handlers = playbook.Handlers
+	return runner
+}
+
+// LoadPlaybook reads the playbook from the file at the given path
+func LoadPlaybook(path string) (*Playbook, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open playbook %s", path)
+	}
+	defer file.Close()
+
+	playbook, err := ParsePlaybook(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse playbook %s", path)
+	}
+	return playbook, nil
+}
+
+// ParsePlaybook reads the playbook from the YAML or JSON document of the reader. The modules of the tasks are created
+// with the registered factories.
+func ParsePlaybook(reader io.Reader) (*Playbook, error) {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read playbook")
+	}
+
+	definition := playbookDefinition{}
+	err = yaml.Unmarshal(content, &definition)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode playbook")
+	}
+
+	playbook := &Playbook{}
+	playbook.Tasks, err = createTasks("task", definition.Tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	playbook.Handlers, err = createTasks("handler", definition.Handlers)
+	if err != nil {
+		return nil, err
+	}
+	return playbook, nil
+}
+
+func createTasks(kind string, definitions []taskDefinition) ([]Task, error) {
+	tasks := []Task{}
+	for index, definition := range definitions {
+		task, err := definition.task()
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s %d (line %d)", kind, index+1, definition.line)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// playbookDefinition is either a list of tasks or a map of tasks and handlers
+type playbookDefinition struct {
+	Tasks    []taskDefinition `yaml:"tasks"`
+	Handlers []taskDefinition `yaml:"handlers"`
+}
+
+func (definition *playbookDefinition) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&definition.Tasks)
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if key != "tasks" && key != "handlers" {
+				return errors.Errorf("unknown playbook attribute %s at line %d", key, node.Content[i].Line)
+			}
+		}
+	}
+
+	type plain playbookDefinition
+	return node.Decode((*plain)(definition))
+}
+
+// taskDefinition is a task of a playbook, before the module is created
+type taskDefinition struct {
+	line   int
+	values map[string]interface{}
+}
+
+func (definition *taskDefinition) UnmarshalYAML(node *yaml.Node) error {
+	definition.line = node.Line
+	return node.Decode(&definition.values)
+}
+
+func (definition *taskDefinition) task() (Task, error) {
+	task := Task{}
+
+	moduleName := ""
+	for key, value := range definition.values {
+		switch key {
+		case "name":
+			name, ok := value.(string)
+			if !ok {
+				return task, &ArgumentError{Field: key, Reason: "expected string"}
+			}
+			task.Name = name
+		case "tags":
+			tags, err := toStrings(value)
+			if err != nil {
+				return task, &ArgumentError{Field: key, Reason: err.Error()}
+			}
+			task.Tags = tags
+		case "notify":
+			notify, err := toStrings(value)
+			if err != nil {
+				return task, &ArgumentError{Field: key, Reason: err.Error()}
+			}
+			task.Notify = notify
+		case "requires":
+			requires, err := toStrings(value)
+			if err != nil {
+				return task, &ArgumentError{Field: key, Reason: err.Error()}
+			}
+			task.Requires = requires
+		case "timeout":
+			timeout, err := toDuration(value)
+			if err != nil {
+				return task, &ArgumentError{Field: key, Reason: err.Error()}
+			}
+			task.Timeout = timeout
+		default:
+			if !IsRegistered(key) {
+				return task, errors.Errorf("unknown module or task attribute %s", key)
+			}
+			if moduleName != "" {
+				return task, errors.Errorf("task declares more than one module: %s and %s", moduleName, key)
+			}
+			moduleName = key
+		}
+	}
+
+	if moduleName == "" {
+		return task, errors.New("task declares no module")
+	}
+
+	args, err := toArguments(definition.values[moduleName])
+	if err != nil {
+		return task, errors.Wrapf(err, "module %s", moduleName)
+	}
+
+	task.Module, err = NewModule(moduleName, args)
+	if err != nil {
+		return task, err
+	}
+
+	if task.Name == "" {
+		task.Name = moduleName
+	}
+	return task, nil
+}
+
+func toArguments(value interface{}) (Arguments, error) {
+	switch args := value.(type) {
+	case nil:
+		return Arguments{}, nil
+	case map[string]interface{}:
+		return Arguments(args), nil
+	default:
+		return nil, errors.Errorf("expected map of arguments, got %T", value)
+	}
+}
+
+func toStrings(value interface{}) ([]string, error) {
+	switch values := value.(type) {
+	case string:
+		return []string{values}, nil
+	case []interface{}:
+		strs := []string{}
+		for _, item := range values {
+			str, ok := item.(string)
+			if !ok {
+				return nil, errors.Errorf("expected list of strings, found %T", item)
+			}
+			strs = append(strs, str)
+		}
+		return strs, nil
+	default:
+		return nil, errors.Errorf("expected string or list of strings, got %T", value)
+	}
+}
+
+// toDuration converts a duration string such as 1m30s or a number of seconds to a duration
+func toDuration(value interface{}) (time.Duration, error) {
+	switch duration := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(duration)
+		if err != nil {
+			return 0, errors.Errorf("expected duration, got %s", duration)
+		}
+		return parsed, nil
+	case int:
+		return time.Duration(duration) * time.Second, nil
+	default:
+		return 0, errors.Errorf("expected duration or number of seconds, got %T", value)
+	}
+}
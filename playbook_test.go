@@ -0,0 +1,150 @@
+package welfare_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdorra/welfare"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	welfare.Register("test", func(args welfare.Arguments) (welfare.Module, error) {
+		arguments := struct {
+			Status string `welfare:"status,required"`
+		}{}
+		err := args.Decode(&arguments)
+		if err != nil {
+			return nil, err
+		}
+
+		module := &testModule{status: welfare.OK}
+		if arguments.Status == "changed" {
+			module.status = welfare.Changed
+		}
+		return module, nil
+	})
+}
+
+func TestParsePlaybook(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- name: first task
+  test: {status: ok}
+- test:
+    status: changed
+`))
+	require.Nil(t, err)
+	require.Len(t, playbook.Tasks, 2)
+	assert.Equal(t, "first task", playbook.Tasks[0].Name)
+	assert.Equal(t, "test", playbook.Tasks[1].Name)
+
+	recap, err := welfare.NewRunner(playbook.Tasks...).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, recap.OK)
+	assert.Equal(t, 1, recap.Changed)
+}
+
+func TestParsePlaybookWithTags(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- test: {status: ok}
+  tags: [config, files]
+- test: {status: ok}
+  tags: packages
+`))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"config", "files"}, playbook.Tasks[0].Tags)
+	assert.Equal(t, []string{"packages"}, playbook.Tasks[1].Tags)
+}
+
+func TestParsePlaybookWithHandlers(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+tasks:
+  - name: changes
+    test: {status: changed}
+    notify: handler
+handlers:
+  - name: handler
+    test: {status: changed}
+`))
+	require.Nil(t, err)
+	require.Len(t, playbook.Tasks, 1)
+	require.Len(t, playbook.Handlers, 1)
+	assert.Equal(t, []string{"handler"}, playbook.Tasks[0].Notify)
+
+	recap, err := playbook.Runner().Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, recap.Changed)
+}
+
+func TestParsePlaybookWithUnknownAttribute(t *testing.T) {
+	_, err := welfare.ParsePlaybook(strings.NewReader(`
+tasks: []
+handler: []
+`))
+	assert.EqualError(t, err, "failed to decode playbook: unknown playbook attribute handler at line 3")
+}
+
+func TestParsePlaybookWithRequires(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- name: one
+  test: {status: ok}
+- name: two
+  requires: [one]
+  test: {status: ok}
+`))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"one"}, playbook.Tasks[1].Requires)
+}
+
+func TestParsePlaybookWithTimeout(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`
+- name: duration
+  timeout: 1m30s
+  test: {status: ok}
+- name: seconds
+  timeout: 10
+  test: {status: ok}
+`))
+	require.Nil(t, err)
+	assert.Equal(t, 90*time.Second, playbook.Tasks[0].Timeout)
+	assert.Equal(t, 10*time.Second, playbook.Tasks[1].Timeout)
+}
+
+func TestParsePlaybookWithInvalidTimeout(t *testing.T) {
+	_, err := welfare.ParsePlaybook(strings.NewReader(`
+- timeout: soon
+  test: {status: ok}
+`))
+	assert.EqualError(t, err, "task 1 (line 2): invalid argument timeout: expected duration, got soon")
+}
+
+func TestParsePlaybookFromJSON(t *testing.T) {
+	playbook, err := welfare.ParsePlaybook(strings.NewReader(`[{"name": "json", "test": {"status": "ok"}}]`))
+	require.Nil(t, err)
+	require.Len(t, playbook.Tasks, 1)
+	assert.Equal(t, "json", playbook.Tasks[0].Name)
+}
+
+func TestParsePlaybookWithUnknownModule(t *testing.T) {
+	_, err := welfare.ParsePlaybook(strings.NewReader(`
+- test: {status: ok}
+- unknown: {}
+`))
+	assert.EqualError(t, err, "task 2 (line 3): unknown module or task attribute unknown")
+}
+
+func TestParsePlaybookWithInvalidArgument(t *testing.T) {
+	_, err := welfare.ParsePlaybook(strings.NewReader(`
+- test: {state: ok}
+`))
+	assert.EqualError(t, err, "task 1 (line 2): module test: invalid argument state: is unknown")
+}
+
+func TestParsePlaybookWithoutModule(t *testing.T) {
+	_, err := welfare.ParsePlaybook(strings.NewReader(`
+- name: nothing
+`))
+	assert.EqualError(t, err, "task 1 (line 2): task declares no module")
+}
@@ -0,0 +1,68 @@
+package welfare
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Factory creates a module from the arguments of a task
+type Factory func(args Arguments) (Module, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Factory)
+)
+
+// Register makes a module factory available by the given name. The module packages register their modules on
+// initialization, so they have to be imported to be usable in playbooks. Register panics if it is called twice with
+// the same name.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic("module " + name + " is already registered")
+	}
+	registry[name] = factory
+}
+
+// IsRegistered returns true if a module with the given name is registered
+func IsRegistered(name string) bool {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	_, exists := registry[name]
+	return exists
+}
+
+// Modules returns the sorted names of all registered modules
+func Modules() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewModule creates the module which is registered with the given name from the arguments
+func NewModule(name string, args Arguments) (Module, error) {
+	registryMutex.RLock()
+	factory, exists := registry[name]
+	registryMutex.RUnlock()
+
+	if !exists {
+		return nil, errors.Errorf("unknown module %s", name)
+	}
+
+	module, err := factory(args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "module %s", name)
+	}
+	return module, nil
+}
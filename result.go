@@ -0,0 +1,94 @@
+package welfare
+
+import (
+	"os"
+	"time"
+)
+
+// Status describes the outcome of a module execution
+type Status int
+
+const (
+	// OK means that the system was already in the declared state
+	OK Status = iota
+	// Changed means that the module has changed the system or would change it in check mode
+	Changed
+	// Skipped means that the module was not executed
+	Skipped
+	// Failed means that the module was not able to ensure the declared state
+	Failed
+)
+
+var statusNames = []string{"ok", "changed", "skipped", "failed"}
+
+func (status Status) String() string {
+	if status < 0 || int(status) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[status]
+}
+
+// State describes the observed state of the resource which is managed by a module
+type State struct {
+	Exists   bool
+	Mode     os.FileMode
+	UID      int
+	GID      int
+	Checksum string
+	Version  string
+	// Link is the path a symbolic link points to
+	Link string
+}
+
+// Command describes an external command which was executed by a module
+type Command struct {
+	Args     []string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Result describes the outcome of a module execution
+type Result struct {
+	Status  Status
+	Message string
+	Before  State
+	After   State
+	Diff    string
+	// Backup is the path of the copy, which was created before the resource was replaced or removed
+	Backup string
+	// ChangedPaths contains the paths, which were changed by a module that manages more than one path
+	ChangedPaths []string
+	Duration     time.Duration
+	Commands     []Command
+	start        time.Time
+}
+
+// NewResult creates a new result and starts measuring the duration of the execution
+func NewResult() *Result {
+	return &Result{start: time.Now()}
+}
+
+// Changed returns true if the module has changed the system
+func (result *Result) Changed() bool {
+	return result.Status == Changed
+}
+
+// Finish sets the status according to changed and err and stops measuring the duration of the execution. Finish
+// returns the result and the error, so that it can be used as the return statement of a module execution.
+func (result *Result) Finish(changed bool, err error) (*Result, error) {
+	if !result.start.IsZero() {
+		result.Duration = time.Since(result.start)
+	}
+
+	if err != nil {
+		result.Status = Failed
+		result.Message = err.Error()
+	} else if changed {
+		result.Status = Changed
+	} else {
+		result.Status = OK
+	}
+
+	return result, err
+}
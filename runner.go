@@ -0,0 +1,377 @@
+package welfare
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Task is a named module, which is executed by a Runner
+type Task struct {
+	Name   string
+	Module Module
+	Tags   []string
+	// Notify contains the names of handlers, which are executed at the end of the run if the task has changed the
+	// system
+	Notify []string
+	// Requires contains the names of tasks, which have to be finished before the task is executed
+	Requires []string
+	// Timeout limits the duration of the task, zero means no limit
+	Timeout time.Duration
+}
+
+func (task Task) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		for _, taskTag := range task.Tags {
+			if tag == taskTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TaskResult is the outcome of a single task
+type TaskResult struct {
+	Name   string
+	Result *Result
+	Err    error
+}
+
+// Recap summarizes the execution of all tasks, similar to the play recap of ansible
+type Recap struct {
+	OK      int
+	Changed int
+	Skipped int
+	Failed  int
+	Results []TaskResult
+}
+
+func (recap *Recap) add(taskResult TaskResult) {
+	switch taskResult.Result.Status {
+	case OK:
+		recap.OK++
+	case Changed:
+		recap.Changed++
+	case Skipped:
+		recap.Skipped++
+	case Failed:
+		recap.Failed++
+	}
+	recap.Results = append(recap.Results, taskResult)
+}
+
+func (recap *Recap) String() string {
+	return fmt.Sprintf("ok=%d changed=%d skipped=%d failed=%d", recap.OK, recap.Changed, recap.Skipped, recap.Failed)
+}
+
+// NewRunner creates a new runner for the given tasks
+func NewRunner(tasks ...Task) *Runner {
+	return &Runner{
+		Tasks: tasks,
+	}
+}
+
+// Runner executes an ordered list of tasks
+type Runner struct {
+	Tasks []Task
+	// Handlers are only executed if they are notified by a changed task, each handler is executed at most once after
+	// all tasks
+	Handlers []Task
+	// KeepGoing continues with the remaining tasks, after a task has failed
+	KeepGoing bool
+	// Check executes every module in check mode
+	Check bool
+	// Tags limits the execution to tasks which have at least one of the tags
+	Tags []string
+	// StartAt skips all tasks before the task with the given name
+	StartAt string
+	// Workers is the maximum number of tasks which are executed concurrently. Tasks are executed concurrently only if
+	// they are independent of each other, tasks without requirements are independent. Zero or one executes the tasks in
+	// order.
+	Workers int
+	// Observer is notified about the lifecycle of every task and handler, nil ignores all notifications
+	Observer Observer
+}
+
+// Add appends a task with the given name and module
+func (runner *Runner) Add(name string, module Module) {
+	runner.Tasks = append(runner.Tasks, Task{Name: name, Module: module})
+}
+
+// AddLegacy appends a task with the given name and a module, which only implements the former Run contract. The module
+// is wrapped with Legacy.
+func (runner *Runner) AddLegacy(name string, module LegacyModule) {
+	runner.Add(name, Legacy(module))
+}
+
+// AddHandler appends a handler with the given name and module
+func (runner *Runner) AddHandler(name string, module Module) {
+	runner.Handlers = append(runner.Handlers, Task{Name: name, Module: module})
+}
+
+// Run executes the tasks with a background context, see RunContext
+func (runner *Runner) Run() (*Recap, error) {
+	return runner.RunContext(context.Background())
+}
+
+// RunContext executes the tasks in order, followed by the notified handlers and summarizes the outcome. RunContext
+// returns the error of the first failed task, but the recap is returned in any case. Handlers are not executed if a
+// task has failed, unless KeepGoing is set. No further tasks are started after the context is done.
+func (runner *Runner) RunContext(ctx context.Context) (*Recap, error) {
+	recap := &Recap{}
+
+	err := runner.validateNotifications()
+	if err != nil {
+		return recap, err
+	}
+
+	tasks, err := runner.selectTasks()
+	if err != nil {
+		return recap, err
+	}
+
+	graph, err := newTaskGraph(tasks, runner.Tasks)
+	if err != nil {
+		return recap, err
+	}
+
+	notified := map[string]bool{}
+	firstErr := runner.executeGraph(ctx, recap, graph, notified)
+	if firstErr != nil && (!runner.KeepGoing || ctx.Err() != nil) {
+		return recap, firstErr
+	}
+
+	handlers := []Task{}
+	for _, handler := range runner.Handlers {
+		if notified[handler.Name] {
+			handlers = append(handlers, handler)
+		}
+	}
+
+	graph, err = newTaskGraph(handlers, runner.Handlers)
+	if err == nil {
+		err = runner.executeGraph(ctx, recap, graph, notified)
+	}
+	if firstErr == nil {
+		firstErr = err
+	}
+	return recap, firstErr
+}
+
+type indexedResult struct {
+	index      int
+	taskResult TaskResult
+}
+
+// executeGraph executes the tasks of the graph as soon as their requirements are finished, at most Workers tasks at
+// the same time. Ready tasks are started in the order of the graph, so the tasks are executed in order, if there is
+// only one worker. Tasks which require a failed task are skipped. executeGraph marks the handlers of changed tasks as
+// notified and returns the error of the first failed task or the error of the context.
+func (runner *Runner) executeGraph(ctx context.Context, recap *Recap, graph *taskGraph, notified map[string]bool) error {
+	workers := runner.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	observer := runner.Observer
+	if observer == nil {
+		observer = NopObserver{}
+	}
+
+	pending := make([]int, len(graph.tasks))
+	ready := []int{}
+	for index := range graph.tasks {
+		pending[index] = len(graph.requires[index])
+		if pending[index] == 0 {
+			ready = append(ready, index)
+		}
+	}
+
+	var firstErr error
+	failed := make([]bool, len(graph.tasks))
+	results := make(chan indexedResult)
+	running := 0
+	stopped := false
+
+	complete := func(index int, taskResult TaskResult) {
+		recap.add(taskResult)
+		notifyFinished(observer, graph.tasks[index], taskResult)
+
+		if taskResult.Result.Changed() {
+			for _, handler := range graph.tasks[index].Notify {
+				notified[handler] = true
+			}
+		}
+
+		if taskResult.Result.Status == Failed {
+			failed[index] = true
+			if taskResult.Err != nil && firstErr == nil {
+				firstErr = errors.Wrapf(taskResult.Err, "task %s failed", taskResult.Name)
+			}
+			if !runner.KeepGoing {
+				stopped = true
+			}
+		}
+
+		for _, dependent := range graph.dependents[index] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = insertSorted(ready, dependent)
+			}
+		}
+	}
+
+	for {
+		if ctx.Err() != nil {
+			stopped = true
+		}
+
+		for !stopped && running < workers && len(ready) > 0 {
+			index := ready[0]
+			ready = ready[1:]
+
+			if required := graph.failedRequirement(index, failed); required != "" {
+				failed[index] = true
+				complete(index, skippedTaskResult(graph.tasks[index], required))
+				continue
+			}
+
+			running++
+			observer.TaskStarted(graph.tasks[index])
+			go func(index int) {
+				results <- indexedResult{index, runner.execute(ctx, graph.tasks[index])}
+			}(index)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		complete(result.index, result.taskResult)
+	}
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = errors.Wrap(ctx.Err(), "execution was interrupted")
+	}
+	return firstErr
+}
+
+// failedRequirement returns the name of a required task which has failed or an empty string
+func (graph *taskGraph) failedRequirement(index int, failed []bool) string {
+	for _, required := range graph.requires[index] {
+		if failed[required] {
+			return graph.tasks[required].Name
+		}
+	}
+	return ""
+}
+
+// skippedTaskResult creates the result of a task, which is skipped because a required task has failed. The skipped
+// task counts as failed for its dependents.
+func skippedTaskResult(task Task, required string) TaskResult {
+	return TaskResult{
+		Name: task.Name,
+		Result: &Result{
+			Status:  Skipped,
+			Message: "required task " + required + " has failed",
+		},
+	}
+}
+
+func insertSorted(indices []int, index int) []int {
+	position := len(indices)
+	for i, candidate := range indices {
+		if candidate > index {
+			position = i
+			break
+		}
+	}
+
+	indices = append(indices, 0)
+	copy(indices[position+1:], indices[position:])
+	indices[position] = index
+	return indices
+}
+
+func (runner *Runner) validateNotifications() error {
+	handlers := map[string]bool{}
+	for _, handler := range runner.Handlers {
+		handlers[handler.Name] = true
+	}
+
+	for _, task := range runner.Tasks {
+		for _, handler := range task.Notify {
+			if !handlers[handler] {
+				return errors.Errorf("task %s notifies unknown handler %s", task.Name, handler)
+			}
+		}
+	}
+	return nil
+}
+
+// selectTasks returns the tasks which are selected by StartAt and Tags
+func (runner *Runner) selectTasks() ([]Task, error) {
+	tasks := runner.Tasks
+	if runner.StartAt != "" {
+		start := -1
+		for index, task := range tasks {
+			if task.Name == runner.StartAt {
+				start = index
+				break
+			}
+		}
+		if start < 0 {
+			return nil, errors.Errorf("could not find task %s to start at", runner.StartAt)
+		}
+		tasks = tasks[start:]
+	}
+
+	if len(runner.Tags) == 0 {
+		return tasks, nil
+	}
+
+	selected := []Task{}
+	for _, task := range tasks {
+		if task.hasAnyTag(runner.Tags) {
+			selected = append(selected, task)
+		}
+	}
+	return selected, nil
+}
+
+func (runner *Runner) execute(ctx context.Context, task Task) TaskResult {
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+
+	result, err := task.Module.Execute(ctx, runner.Check)
+	if err != nil && task.Timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		err = errors.Wrapf(err, "task timed out after %s", task.Timeout)
+	}
+	if result == nil {
+		result = &Result{}
+		if err == nil {
+			err = errors.New("module returned no result")
+		}
+	}
+
+	if err != nil {
+		result.Status = Failed
+		if result.Message == "" {
+			result.Message = err.Error()
+		}
+	}
+
+	return TaskResult{
+		Name:   task.Name,
+		Result: result,
+		Err:    err,
+	}
+}
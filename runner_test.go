@@ -0,0 +1,337 @@
+package welfare_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdorra/welfare"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Run(t *testing.T) {
+	one := &testModule{status: welfare.OK}
+	two := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner()
+	runner.Add("one", one)
+	runner.Add("two", two)
+
+	recap, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, recap.OK)
+	assert.Equal(t, 1, recap.Changed)
+	assert.Equal(t, 0, recap.Failed)
+	assert.Equal(t, "one", recap.Results[0].Name)
+	assert.Equal(t, "two", recap.Results[1].Name)
+	assert.Equal(t, 1, one.executions)
+	assert.Equal(t, 1, two.executions)
+}
+
+func TestRunner_RunWithLegacyModule(t *testing.T) {
+	runner := welfare.NewRunner()
+	runner.AddLegacy("legacy", &legacyModule{changed: true})
+
+	recap, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, recap.Changed)
+	assert.Equal(t, "legacy", recap.Results[0].Name)
+}
+
+func TestRunner_RunStopsOnFailure(t *testing.T) {
+	one := &testModule{err: errors.New("failed")}
+	two := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner()
+	runner.Add("one", one)
+	runner.Add("two", two)
+
+	recap, err := runner.Run()
+	assert.EqualError(t, err, "task one failed: failed")
+	assert.Equal(t, 1, recap.Failed)
+	assert.Len(t, recap.Results, 1)
+	assert.Equal(t, 0, two.executions)
+}
+
+func TestRunner_RunKeepGoing(t *testing.T) {
+	one := &testModule{err: errors.New("failed")}
+	two := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner()
+	runner.KeepGoing = true
+	runner.Add("one", one)
+	runner.Add("two", two)
+
+	recap, err := runner.Run()
+	assert.Error(t, err)
+	assert.Equal(t, 1, recap.Failed)
+	assert.Equal(t, 1, recap.Changed)
+	assert.Equal(t, 1, two.executions)
+	assert.Equal(t, "ok=0 changed=1 skipped=0 failed=1", recap.String())
+}
+
+func TestRunner_RunInCheckMode(t *testing.T) {
+	one := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner(welfare.Task{Name: "one", Module: one})
+	runner.Check = true
+
+	_, err := runner.Run()
+	assert.Nil(t, err)
+	assert.True(t, one.check)
+}
+
+func TestRunner_RunWithTags(t *testing.T) {
+	one := &testModule{status: welfare.Changed}
+	two := &testModule{status: welfare.Changed}
+	three := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "one", Module: one, Tags: []string{"config"}},
+		welfare.Task{Name: "two", Module: two},
+		welfare.Task{Name: "three", Module: three, Tags: []string{"packages", "config"}},
+	)
+	runner.Tags = []string{"config"}
+
+	recap, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, recap.Changed)
+	assert.Equal(t, 0, two.executions)
+}
+
+func TestRunner_RunStartAt(t *testing.T) {
+	one := &testModule{status: welfare.Changed}
+	two := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner()
+	runner.Add("one", one)
+	runner.Add("two", two)
+	runner.StartAt = "two"
+
+	recap, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Len(t, recap.Results, 1)
+	assert.Equal(t, 0, one.executions)
+	assert.Equal(t, 1, two.executions)
+}
+
+func TestRunner_RunStartAtUnknownTask(t *testing.T) {
+	runner := welfare.NewRunner()
+	runner.Add("one", &testModule{})
+	runner.StartAt = "two"
+
+	_, err := runner.Run()
+	assert.EqualError(t, err, "could not find task two to start at")
+}
+
+func TestRunner_RunWithHandlers(t *testing.T) {
+	handler := &testModule{status: welfare.Changed}
+	unnotified := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "one", Module: &testModule{status: welfare.Changed}, Notify: []string{"handler"}},
+		welfare.Task{Name: "two", Module: &testModule{status: welfare.Changed}, Notify: []string{"handler"}},
+		welfare.Task{Name: "three", Module: &testModule{status: welfare.OK}, Notify: []string{"unnotified"}},
+	)
+	runner.AddHandler("unnotified", unnotified)
+	runner.AddHandler("handler", handler)
+
+	recap, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, handler.executions)
+	assert.Equal(t, 0, unnotified.executions)
+	assert.Len(t, recap.Results, 4)
+	assert.Equal(t, "handler", recap.Results[3].Name)
+}
+
+func TestRunner_RunWithHandlersAfterFailure(t *testing.T) {
+	handler := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "one", Module: &testModule{status: welfare.Changed}, Notify: []string{"handler"}},
+		welfare.Task{Name: "two", Module: &testModule{err: errors.New("failed")}},
+	)
+	runner.AddHandler("handler", handler)
+
+	_, err := runner.Run()
+	assert.Error(t, err)
+	assert.Equal(t, 0, handler.executions)
+
+	runner.KeepGoing = true
+	_, err = runner.Run()
+	assert.Error(t, err)
+	assert.Equal(t, 1, handler.executions)
+}
+
+func TestRunner_RunWithUnknownHandler(t *testing.T) {
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "one", Module: &testModule{}, Notify: []string{"handler"}},
+	)
+
+	_, err := runner.Run()
+	assert.EqualError(t, err, "task one notifies unknown handler handler")
+}
+
+func TestRunner_RunWithRequires(t *testing.T) {
+	order := []string{}
+	module := func(name string) welfare.Module {
+		return &recordingModule{name: name, order: &order}
+	}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "package", Module: module("package"), Requires: []string{"repository"}},
+		welfare.Task{Name: "repository", Module: module("repository"), Requires: []string{"key"}},
+		welfare.Task{Name: "key", Module: module("key")},
+		welfare.Task{Name: "file", Module: module("file")},
+	)
+
+	_, err := runner.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"key", "repository", "package", "file"}, order)
+}
+
+func TestRunner_RunWithCycle(t *testing.T) {
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "a", Module: &testModule{}, Requires: []string{"c"}},
+		welfare.Task{Name: "b", Module: &testModule{}, Requires: []string{"a"}},
+		welfare.Task{Name: "c", Module: &testModule{}, Requires: []string{"b"}},
+	)
+
+	_, err := runner.Run()
+	assert.EqualError(t, err, "dependency cycle detected: a -> c -> b -> a")
+}
+
+func TestRunner_RunWithUnknownRequirement(t *testing.T) {
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "a", Module: &testModule{}, Requires: []string{"b"}},
+	)
+
+	_, err := runner.Run()
+	assert.EqualError(t, err, "task a requires unknown task b")
+}
+
+func TestRunner_RunSkipsDependentsOfFailedTasks(t *testing.T) {
+	dependent := &testModule{}
+	transitive := &testModule{}
+	independent := &testModule{}
+
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "a", Module: &testModule{err: errors.New("failed")}},
+		welfare.Task{Name: "b", Module: dependent, Requires: []string{"a"}},
+		welfare.Task{Name: "c", Module: transitive, Requires: []string{"b"}},
+		welfare.Task{Name: "d", Module: independent},
+	)
+	runner.KeepGoing = true
+
+	recap, err := runner.Run()
+	assert.EqualError(t, err, "task a failed: failed")
+	assert.Equal(t, 1, recap.Failed)
+	assert.Equal(t, 2, recap.Skipped)
+	assert.Equal(t, 1, recap.OK)
+	assert.Equal(t, 0, dependent.executions)
+	assert.Equal(t, 0, transitive.executions)
+	assert.Equal(t, 1, independent.executions)
+}
+
+func TestRunner_RunConcurrently(t *testing.T) {
+	started := make(chan bool)
+	release := make(chan bool)
+
+	runner := welfare.NewRunner()
+	runner.Workers = 2
+	runner.Add("one", &blockingModule{started, release})
+	runner.Add("two", &blockingModule{started, release})
+
+	done := make(chan bool)
+	go func() {
+		recap, err := runner.Run()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, recap.Changed)
+		close(done)
+	}()
+
+	// both tasks have to be started, before any of them is released
+	<-started
+	<-started
+	close(release)
+	<-done
+}
+
+func TestRunner_RunWithTimeout(t *testing.T) {
+	runner := welfare.NewRunner(
+		welfare.Task{Name: "slow", Module: &waitingModule{}, Timeout: 10 * time.Millisecond},
+	)
+
+	recap, err := runner.Run()
+	assert.EqualError(t, err, "task slow failed: task timed out after 10ms: context deadline exceeded")
+	assert.Equal(t, 1, recap.Failed)
+}
+
+func TestRunner_RunContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	two := &testModule{status: welfare.Changed}
+
+	runner := welfare.NewRunner()
+	runner.KeepGoing = true
+	runner.Add("one", &cancelingModule{cancel})
+	runner.Add("two", two)
+
+	recap, err := runner.RunContext(ctx)
+	assert.EqualError(t, err, "execution was interrupted: context canceled")
+	assert.Len(t, recap.Results, 1)
+	assert.Equal(t, 0, two.executions)
+}
+
+type waitingModule struct{}
+
+func (module *waitingModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	<-ctx.Done()
+	return welfare.NewResult().Finish(false, ctx.Err())
+}
+
+type cancelingModule struct {
+	cancel context.CancelFunc
+}
+
+func (module *cancelingModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	module.cancel()
+	return &welfare.Result{Status: welfare.Changed}, nil
+}
+
+type recordingModule struct {
+	name  string
+	order *[]string
+}
+
+func (module *recordingModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	*module.order = append(*module.order, module.name)
+	return &welfare.Result{Status: welfare.OK}, nil
+}
+
+type blockingModule struct {
+	started chan bool
+	release chan bool
+}
+
+func (module *blockingModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	module.started <- true
+	<-module.release
+	return &welfare.Result{Status: welfare.Changed}, nil
+}
+
+type testModule struct {
+	status     welfare.Status
+	err        error
+	check      bool
+	executions int
+}
+
+func (module *testModule) Execute(ctx context.Context, check bool) (*welfare.Result, error) {
+	module.executions++
+	module.check = check
+	if module.err != nil {
+		return welfare.NewResult().Finish(false, module.err)
+	}
+	return &welfare.Result{Status: module.status}, nil
+}